@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,18 +11,23 @@ import (
 	"text/template"
 	"webapp-go/migrations"
 	"webapp-go/webapp"
+	"webapp-go/webapp/blobs"
 	"webapp-go/webapp/config"
 	"webapp-go/webapp/controllers"
 	"webapp-go/webapp/middlewares"
 	"webapp-go/webapp/models"
+	"webapp-go/webapp/parsers"
 	"webapp-go/webapp/repositories"
 	"webapp-go/webapp/services"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/migrate"
+	"gopkg.in/yaml.v3"
 
 	"github.com/urfave/cli/v2"
 )
@@ -230,13 +236,344 @@ func newAppCommand(cfg config.Config) *cli.Command {
 					return runApp(cfg)
 				},
 			},
+			{
+				Name:  "reindex",
+				Usage: "re-embed the documents of all posts or of a single post",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "post", Usage: "slug of the post to reindex"},
+				},
+				Action: func(c *cli.Context) error {
+					return runReindex(cfg, c)
+				},
+			},
+			{
+				Name:  "sync-git",
+				Usage: "sync the documents of the posts linked to a git repository",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "post", Usage: "slug of the post to sync"},
+				},
+				Action: func(c *cli.Context) error {
+					return runSyncGit(cfg, c)
+				},
+			},
+			{
+				Name:  "migrate-blobs",
+				Usage: "copy the content of the documents from another blob backend to the configured one",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "from", Usage: "backend to copy from (postgres, filesystem or s3)", Required: true},
+					&cli.BoolFlag{Name: "delete-source", Usage: "delete the blobs from the source backend once copied"},
+				},
+				Action: func(c *cli.Context) error {
+					return runMigrateBlobs(cfg, c)
+				},
+			},
+			{
+				Name:  "eval",
+				Usage: "evaluate the retrieval of a post against a dataset of questions",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "post", Usage: "slug of the post to evaluate", Required: true},
+					&cli.StringFlag{Name: "dataset", Usage: "yaml file with the questions", Required: true},
+					&cli.IntFlag{Name: "k", Usage: "number of documents to retrieve", Value: 3},
+					&cli.BoolFlag{Name: "judge", Usage: "score the generated answers against the reference answers"},
+					&cli.StringFlag{Name: "format", Usage: "output format (markdown or json)", Value: "markdown"},
+				},
+				Action: func(c *cli.Context) error {
+					return runEval(cfg, c)
+				},
+			},
 		},
 	}
 }
 
+// newEmbeddingsService wires the embeddings service for the commands that run
+// outside of the web application.
+func newEmbeddingsService(cfg config.Config, db *bun.DB, blobStore blobs.BlobStore, llm *ollama.LLM, documentChan <-chan models.DocumentChanItem) services.EmbeddingsService {
+	postsRepository := repositories.NewPostsRepository(db, blobStore)
+	usersRepository := repositories.NewUserRepository(db)
+	documentsRepository := repositories.NewDocumentsRepository(db, blobStore)
+	embeddingRepository := repositories.NewEmbeddingsRepository(db)
+	searchCacheRepository := repositories.NewSearchCacheRepository(db)
+	answersRepository := repositories.NewAnswersRepository(db)
+	historyRepository := repositories.NewHistoryRepository(db)
+
+	historyService := services.NewHistoryService(usersRepository, historyRepository)
+
+	return services.NewEmbeddingsService(cfg, postsRepository, documentsRepository, embeddingRepository, searchCacheRepository, answersRepository, historyService, llm, documentChan)
+}
+
+// configureParsers registers again the parsers that depend on the config.
+func configureParsers(cfg config.Config) {
+	parsers.RegisterContentType("application/x-ipynb+json", parsers.NotebookParser{IncludeOutputs: cfg.Notebooks.IncludeOutputs})
+}
+
+func runReindex(cfg config.Config, c *cli.Context) error {
+	ctx := context.Background()
+
+	configureParsers(cfg)
+
+	db := webapp.DBConnection(cfg)
+
+	defer db.Close()
+
+	llm, err := ollama.New(ollama.WithServerURL(cfg.Ollama.Url), ollama.WithModel(cfg.Ollama.Model))
+	if err != nil {
+		return err
+	}
+
+	blobStore, err := blobs.New(cfg.Blobs.Backend, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	postsRepository := repositories.NewPostsRepository(db, blobStore)
+	documentsRepository := repositories.NewDocumentsRepository(db, blobStore)
+
+	slugs := []uuid.UUID{}
+	if c.IsSet("post") {
+		slug, err := uuid.Parse(c.String("post"))
+		if err != nil {
+			return err
+		}
+
+		slugs = append(slugs, slug)
+	} else {
+		posts, err := postsRepository.GetPosts(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range posts {
+			slugs = append(slugs, p.Slug)
+		}
+	}
+
+	documentChan := make(chan models.DocumentChanItem, 128)
+	embeddingsService := newEmbeddingsService(cfg, db, blobStore, llm, documentChan)
+
+	done := make(chan struct{})
+	go func() {
+		embeddingsService.Worker(ctx)
+		close(done)
+	}()
+
+	count := 0
+	for _, slug := range slugs {
+		documents, err := documentsRepository.GetDocuments(ctx, slug)
+		if err != nil {
+			close(documentChan)
+			<-done
+			return err
+		}
+
+		for _, d := range documents {
+			documentChan <- models.NewDocumentChanItem(models.UPDATE, d.PostSlug, d.ID)
+			count++
+		}
+	}
+
+	close(documentChan)
+	<-done
+
+	fmt.Printf("reindexed %d documents\n", count)
+
+	return nil
+}
+
+func runMigrateBlobs(cfg config.Config, c *cli.Context) error {
+	ctx := context.Background()
+
+	if c.String("from") == cfg.Blobs.Backend {
+		return fmt.Errorf("the blobs are already in the %s backend", cfg.Blobs.Backend)
+	}
+
+	db := webapp.DBConnection(cfg)
+
+	defer db.Close()
+
+	from, err := blobs.New(c.String("from"), cfg, db)
+	if err != nil {
+		return err
+	}
+
+	to, err := blobs.New(cfg.Blobs.Backend, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	documentsRepository := repositories.NewDocumentsRepository(db, to)
+
+	hashes, err := documentsRepository.GetContentHashes(ctx)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for key, hash := range hashes {
+		content, err := from.Get(ctx, key)
+		if errors.Is(err, blobs.ErrNotFound) {
+			// Already moved by a previous run
+			if _, err := to.Get(ctx, key); err == nil {
+				continue
+			}
+			return fmt.Errorf("blob %s is in neither backend", key)
+		}
+		if err != nil {
+			return err
+		}
+
+		if models.ContentHash(content) != hash {
+			return fmt.Errorf("blob %s does not match its hash", key)
+		}
+
+		if err := to.Put(ctx, key, content); err != nil {
+			return err
+		}
+
+		if c.Bool("delete-source") {
+			if err := from.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+
+		count++
+	}
+
+	fmt.Printf("moved %d blobs from %s to %s\n", count, c.String("from"), cfg.Blobs.Backend)
+
+	return nil
+}
+
+func runSyncGit(cfg config.Config, c *cli.Context) error {
+	ctx := context.Background()
+
+	configureParsers(cfg)
+
+	db := webapp.DBConnection(cfg)
+
+	defer db.Close()
+
+	llm, err := ollama.New(ollama.WithServerURL(cfg.Ollama.Url), ollama.WithModel(cfg.Ollama.Model))
+	if err != nil {
+		return err
+	}
+
+	blobStore, err := blobs.New(cfg.Blobs.Backend, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	postsRepository := repositories.NewPostsRepository(db, blobStore)
+	documentsRepository := repositories.NewDocumentsRepository(db, blobStore)
+	searchCacheRepository := repositories.NewSearchCacheRepository(db)
+	gitSourcesRepository := repositories.NewGitSourcesRepository(db)
+
+	slugs := []uuid.UUID{}
+	if c.IsSet("post") {
+		slug, err := uuid.Parse(c.String("post"))
+		if err != nil {
+			return err
+		}
+
+		slugs = append(slugs, slug)
+	} else {
+		sources, err := gitSourcesRepository.GetSources(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range sources {
+			slugs = append(slugs, s.PostSlug)
+		}
+	}
+
+	documentChan := make(chan models.DocumentChanItem, 128)
+	embeddingsService := newEmbeddingsService(cfg, db, blobStore, llm, documentChan)
+	gitService := services.NewGitService(cfg, gitSourcesRepository, documentsRepository, postsRepository, searchCacheRepository, documentChan, nil)
+
+	done := make(chan struct{})
+	go func() {
+		embeddingsService.Worker(ctx)
+		close(done)
+	}()
+
+	for _, slug := range slugs {
+		result, err := gitService.Sync(ctx, slug)
+		if err != nil {
+			close(documentChan)
+			<-done
+			return err
+		}
+
+		fmt.Printf("%s: synced %s, %d created, %d updated, %d deleted, %d unchanged, %d skipped\n", slug, result.Commit, len(result.Created), len(result.Updated), len(result.Deleted), result.Unchanged, len(result.Skipped))
+	}
+
+	close(documentChan)
+	<-done
+
+	return nil
+}
+
+func runEval(cfg config.Config, c *cli.Context) error {
+	ctx := context.Background()
+
+	slug, err := uuid.Parse(c.String("post"))
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(c.String("dataset"))
+	if err != nil {
+		return err
+	}
+
+	dataset := models.EvalDataset{}
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		return err
+	}
+
+	db := webapp.DBConnection(cfg)
+
+	defer db.Close()
+
+	llm, err := ollama.New(ollama.WithServerURL(cfg.Ollama.Url), ollama.WithModel(cfg.Ollama.Model))
+	if err != nil {
+		return err
+	}
+
+	blobStore, err := blobs.New(cfg.Blobs.Backend, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	embeddingsService := newEmbeddingsService(cfg, db, blobStore, llm, nil)
+	evalService := services.NewEvalService(cfg, embeddingsService, llm)
+
+	report, err := evalService.Run(ctx, slug, dataset, c.Int("k"), c.Bool("judge"))
+	if err != nil {
+		return err
+	}
+
+	switch c.String("format") {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "markdown":
+		fmt.Print(report.FormatMarkdown())
+	default:
+		return fmt.Errorf("unknown format %s", c.String("format"))
+	}
+
+	return nil
+}
+
 func runApp(cfg config.Config) error {
 	ctx := context.Background()
 
+	configureParsers(cfg)
+
 	db := webapp.DBConnection(cfg)
 
 	defer db.Close()
@@ -248,25 +585,42 @@ func runApp(cfg config.Config) error {
 		return err
 	}
 
+	blobStore, err := blobs.New(cfg.Blobs.Backend, cfg, db)
+	if err != nil {
+		return err
+	}
+
 	documentChan := make(chan models.DocumentChanItem, 128)
+	pushChan := make(chan models.GitPushChanItem, max(cfg.Git.WebhookQueueSize, 1))
 
-	postsRepository := repositories.NewPostsRepository(db)
+	postsRepository := repositories.NewPostsRepository(db, blobStore)
 	usersRepository := repositories.NewUserRepository(db)
-	documentsRepository := repositories.NewDocumentsRepository(db)
+	documentsRepository := repositories.NewDocumentsRepository(db, blobStore)
 	embeddingRepository := repositories.NewEmbeddingsRepository(db)
+	searchCacheRepository := repositories.NewSearchCacheRepository(db)
+	answersRepository := repositories.NewAnswersRepository(db)
+	historyRepository := repositories.NewHistoryRepository(db)
+	gitSourcesRepository := repositories.NewGitSourcesRepository(db)
 
 	authService := services.NewAuthService(cfg)
 	usersService := services.NewUsersService(usersRepository)
 	bearerService := services.NewBearerService(cfg)
-	embeddingsService := services.NewEmbeddingsService(documentsRepository, embeddingRepository, llm, documentChan)
+	historyService := services.NewHistoryService(usersRepository, historyRepository)
+	embeddingsService := services.NewEmbeddingsService(cfg, postsRepository, documentsRepository, embeddingRepository, searchCacheRepository, answersRepository, historyService, llm, documentChan)
+	importService := services.NewImportService(cfg)
+	gitService := services.NewGitService(cfg, gitSourcesRepository, documentsRepository, postsRepository, searchCacheRepository, documentChan, pushChan)
 
 	postsController := controllers.NewPostsController(postsRepository, usersRepository)
-	viewController := controllers.NewViewController(postsRepository, usersRepository, documentsRepository, embeddingsService)
+	viewController := controllers.NewViewController(postsRepository, usersRepository, documentsRepository, embeddingsService, historyService)
 	authController := controllers.NewAuthController(cfg, authService, usersService, bearerService)
-	documentsController := controllers.NewDocumentsController(documentsRepository, postsRepository, documentChan)
-	embeddingsController := controllers.NewEmbeddingsController(documentsRepository, embeddingsService)
+	documentsController := controllers.NewDocumentsController(cfg, documentsRepository, postsRepository, searchCacheRepository, importService, embeddingsService, documentChan)
+	embeddingsController := controllers.NewEmbeddingsController(documentsRepository, postsRepository, embeddingsService)
+	feedbackController := controllers.NewFeedbackController(answersRepository, postsRepository)
+	historyController := controllers.NewHistoryController(historyService)
+	gitController := controllers.NewGitController(gitSourcesRepository, postsRepository, gitService, pushChan)
 
 	go embeddingsService.Worker(ctx)
+	go gitService.Worker(ctx)
 
 	router := gin.Default()
 
@@ -300,14 +654,33 @@ func runApp(cfg config.Config) error {
 	authorized.DELETE("/api/posts/:slug", postsController.DeletePost)
 
 	authorized.GET("/api/posts/:slug/documents/:id", documentsController.GetDocument)
+	authorized.GET("/api/posts/:slug/documents/:id/raw", documentsController.GetDocumentRaw)
 	authorized.GET("/api/posts/:slug/documents", documentsController.GetDocuments)
 	authorized.POST("/api/posts/:slug/documents", documentsController.CreateDocument)
+	authorized.POST("/api/posts/:slug/documents/import", documentsController.ImportDocument)
 	authorized.PUT("/api/posts/:slug/documents/:id", documentsController.UpdateDocument)
 	authorized.DELETE("/api/posts/:slug/documents/:id", documentsController.DeleteDocument)
+	authorized.GET("/api/posts/:slug/documents/:id/revisions", documentsController.GetRevisions)
+	authorized.GET("/api/posts/:slug/documents/:id/revisions/diff", documentsController.DiffRevisions)
+	authorized.POST("/api/posts/:slug/documents/:id/revisions/:revisionId/restore", documentsController.RestoreRevision)
+
+	authorized.GET("/api/posts/:slug/git", gitController.GetSource)
+	authorized.PUT("/api/posts/:slug/git", gitController.SaveSource)
+	authorized.DELETE("/api/posts/:slug/git", gitController.DeleteSource)
+	authorized.POST("/api/posts/:slug/git/sync", gitController.SyncSource)
+	authorized.GET("/api/posts/:slug/git/deliveries", gitController.GetDeliveries)
+	router.POST("/api/posts/:slug/git/webhook", gitController.Webhook)
 
 	authorized.GET("/api/search/:slug", embeddingsController.GetSearchResult)
 
+	authorized.POST("/api/answers/:id/feedback", feedbackController.CreateFeedback)
+	authorized.GET("/api/posts/:slug/feedback", feedbackController.GetFeedbackReport)
+
 	authorized.GET("/api/user", authController.GetUser)
+	authorized.GET("/api/user/history", historyController.GetHistory)
+	authorized.PUT("/api/user/history", historyController.UpdateHistorySettings)
+	authorized.DELETE("/api/user/history", historyController.ClearHistory)
+	authorized.DELETE("/api/user/history/:id", historyController.DeleteHistoryEntry)
 	authorized.GET("/api/bearer", authController.BearerToken)
 
 	router.GET("/", viewController.GetIndexPage)
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewAddColumn().
+			Model((*models.Post)(nil)).
+			ColumnExpr("document_version integer NOT NULL DEFAULT 0").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateTable().
+			Model((*models.SearchCacheEntry)(nil)).
+			ForeignKey(`("post_slug") REFERENCES "posts" ("slug") ON DELETE CASCADE`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropTable().
+			Model((*models.SearchCacheEntry)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewDropColumn().
+			Model((*models.Post)(nil)).
+			Column("document_version").
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewCreateTable().
+			Model((*models.EmbeddingCacheEntry)(nil)).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropTable().
+			Model((*models.EmbeddingCacheEntry)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
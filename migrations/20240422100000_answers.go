@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewCreateTable().
+			Model((*models.SearchAnswer)(nil)).
+			ForeignKey(`("post_slug") REFERENCES "posts" ("slug") ON DELETE CASCADE`).
+			ForeignKey(`("user_id") REFERENCES "users" ("id") ON DELETE CASCADE`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateTable().
+			Model((*models.AnswerFeedback)(nil)).
+			ForeignKey(`("answer_id") REFERENCES "search_answers" ("id") ON DELETE CASCADE`).
+			ForeignKey(`("user_id") REFERENCES "users" ("id") ON DELETE CASCADE`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropTable().
+			Model((*models.AnswerFeedback)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewDropTable().
+			Model((*models.SearchAnswer)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
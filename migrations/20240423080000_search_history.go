@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewAddColumn().
+			Model((*models.User)(nil)).
+			ColumnExpr("search_history boolean NOT NULL DEFAULT true").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateTable().
+			Model((*models.SearchHistoryEntry)(nil)).
+			ForeignKey(`("user_id") REFERENCES "users" ("id") ON DELETE CASCADE`).
+			ForeignKey(`("post_slug") REFERENCES "posts" ("slug") ON DELETE CASCADE`).
+			ForeignKey(`("answer_id") REFERENCES "search_answers" ("id") ON DELETE SET NULL`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropTable().
+			Model((*models.SearchHistoryEntry)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewDropColumn().
+			Model((*models.User)(nil)).
+			Column("search_history").
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
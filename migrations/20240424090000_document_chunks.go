@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewAddColumn().
+			Model((*models.DocumentEmbedding)(nil)).
+			ColumnExpr("chunk_index integer NOT NULL DEFAULT 0").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewAddColumn().
+			Model((*models.DocumentEmbedding)(nil)).
+			ColumnExpr("content text NOT NULL DEFAULT ''").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewAddColumn().
+			Model((*models.DocumentEmbedding)(nil)).
+			ColumnExpr("page integer NOT NULL DEFAULT 0").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		// A document now has one embedding per chunk
+		_, err = db.ExecContext(ctx, `ALTER TABLE "document_embeddings" DROP CONSTRAINT IF EXISTS "document_embeddings_document_id_key"`)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateIndex().
+			Model((*models.DocumentEmbedding)(nil)).
+			Index("document_embeddings_document_chunk_idx").
+			Column("document_id", "chunk_index").
+			Unique().
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropIndex().
+			Model((*models.DocumentEmbedding)(nil)).
+			Index("document_embeddings_document_chunk_idx").
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		// Only the first chunk of every document can be kept
+		_, err = db.NewDelete().
+			Model((*models.DocumentEmbedding)(nil)).
+			Where("chunk_index > 0").
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, column := range []string{"chunk_index", "content", "page"} {
+			_, err = db.NewDropColumn().
+				Model((*models.DocumentEmbedding)(nil)).
+				Column(column).
+				Exec(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		_, err = db.ExecContext(ctx, `ALTER TABLE "document_embeddings" ADD CONSTRAINT "document_embeddings_document_id_key" UNIQUE ("document_id")`)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewAddColumn().
+			Model((*models.Document)(nil)).
+			ColumnExpr("metadata jsonb NOT NULL DEFAULT '{}'").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropColumn().
+			Model((*models.Document)(nil)).
+			Column("metadata").
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
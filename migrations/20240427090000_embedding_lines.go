@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		for _, column := range []string{"start_line", "end_line"} {
+			_, err := db.NewAddColumn().
+				Model((*models.DocumentEmbedding)(nil)).
+				ColumnExpr("? integer NOT NULL DEFAULT 0", bun.Ident(column)).
+				IfNotExists().
+				Exec(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		for _, column := range []string{"start_line", "end_line"} {
+			_, err := db.NewDropColumn().
+				Model((*models.DocumentEmbedding)(nil)).
+				Column(column).
+				Exec(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		// The tables of a new database are created from the models, which no
+		// longer hold the content since it moved to the blob store. Until the
+		// blob storage migration has run, the content columns are made for the
+		// document revisions migration to copy the content, and the blob
+		// storage migration then moves it to the blobs and drops them.
+		if hasTable(ctx, db, "blobs") {
+			return nil
+		}
+
+		_, err := db.NewAddColumn().
+			Model((*models.Document)(nil)).
+			ColumnExpr("content bytea NOT NULL DEFAULT ''").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateTable().
+			Model((*models.DocumentRevision)(nil)).
+			ForeignKey(`("document_id") REFERENCES "documents" ("id") ON DELETE CASCADE`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewAddColumn().
+			Model((*models.DocumentRevision)(nil)).
+			ColumnExpr("content bytea NOT NULL DEFAULT ''").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		// The content columns are dropped by the blob storage migration and the
+		// revisions by the document revisions migration
+		return nil
+	})
+}
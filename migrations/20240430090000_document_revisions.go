@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewCreateTable().
+			Model((*models.DocumentRevision)(nil)).
+			ForeignKey(`("document_id") REFERENCES "documents" ("id") ON DELETE CASCADE`).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.NewCreateIndex().
+			Model((*models.DocumentRevision)(nil)).
+			Index("document_revisions_document_id_idx").
+			Column("document_id", "created_at").
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		// The existing documents start their history with their current content
+		_, err = db.NewRaw(`INSERT INTO "document_revisions" ("document_id", "post_slug", "filename", "content_type", "content", "created_at")
+			SELECT "id", "post_slug", "filename", "content_type", "content", "created_at" FROM "documents" d
+			WHERE NOT EXISTS (SELECT 1 FROM "document_revisions" dr WHERE dr."document_id" = d."id")`).
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		_, err := db.NewDropTable().
+			Model((*models.DocumentRevision)(nil)).
+			IfExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
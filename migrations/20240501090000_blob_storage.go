@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		_, err := db.NewCreateTable().
+			Model((*models.Blob)(nil)).
+			IfNotExists().
+			Exec(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, model := range []interface{}{(*models.Document)(nil), (*models.DocumentRevision)(nil)} {
+			for _, column := range []string{
+				"content_key varchar(256) NOT NULL DEFAULT ''",
+				"content_hash varchar(64) NOT NULL DEFAULT ''",
+				"size bigint NOT NULL DEFAULT 0",
+			} {
+				_, err = db.NewAddColumn().
+					Model(model).
+					ColumnExpr(column).
+					IfNotExists().
+					Exec(ctx)
+				if err != nil {
+					panic(err)
+				}
+			}
+		}
+
+		// The content moves to the blobs table, which is the default blob store.
+		// A revision with the content of its document shares the blob.
+		err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if hasColumn(ctx, tx, "documents", "content") {
+				_, err := tx.ExecContext(ctx, `UPDATE "documents" SET
+					"content_key" = "post_slug"::text || '/' || "id"::text,
+					"content_hash" = encode(sha256("content"), 'hex'),
+					"size" = octet_length("content")
+					WHERE "content_key" = ''`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `INSERT INTO "blobs" ("key", "content")
+					SELECT "content_key", "content" FROM "documents"
+					ON CONFLICT ("key") DO NOTHING`)
+				if err != nil {
+					return err
+				}
+			}
+
+			if hasColumn(ctx, tx, "document_revisions", "content") {
+				_, err := tx.ExecContext(ctx, `UPDATE "document_revisions" dr SET
+					"content_hash" = encode(sha256(dr."content"), 'hex'),
+					"size" = octet_length(dr."content"),
+					"content_key" = COALESCE(
+						(SELECT d."content_key" FROM "documents" d WHERE d."id" = dr."document_id" AND d."content_hash" = encode(sha256(dr."content"), 'hex')),
+						dr."post_slug"::text || '/' || dr."id"::text
+					)
+					WHERE dr."content_key" = ''`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `INSERT INTO "blobs" ("key", "content")
+					SELECT "content_key", "content" FROM "document_revisions"
+					ON CONFLICT ("key") DO NOTHING`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.NewDropColumn().Model((*models.DocumentRevision)(nil)).Column("content").Exec(ctx)
+				if err != nil {
+					return err
+				}
+			}
+
+			if hasColumn(ctx, tx, "documents", "content") {
+				_, err := tx.NewDropColumn().Model((*models.Document)(nil)).Column("content").Exec(ctx)
+				if err != nil {
+					return err
+				}
+			}
+
+			// The documents without history start it with their current content
+			_, err := tx.ExecContext(ctx, `INSERT INTO "document_revisions" ("document_id", "post_slug", "filename", "content_type", "content_key", "content_hash", "size", "created_at")
+				SELECT "id", "post_slug", "filename", "content_type", "content_key", "content_hash", "size", "created_at" FROM "documents" d
+				WHERE NOT EXISTS (SELECT 1 FROM "document_revisions" dr WHERE dr."document_id" = d."id")`)
+
+			return err
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		// Only the content kept by the Postgres blob store can be brought back,
+		// the blobs of the other stores must be moved to it first
+		err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			for _, table := range []string{"documents", "document_revisions"} {
+				_, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %q ADD COLUMN IF NOT EXISTS "content" bytea NOT NULL DEFAULT ''`, table))
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %q t SET "content" = b."content" FROM "blobs" b WHERE b."key" = t."content_key"`, table))
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, model := range []interface{}{(*models.Document)(nil), (*models.DocumentRevision)(nil)} {
+				for _, column := range []string{"content_key", "content_hash", "size"} {
+					_, err := tx.NewDropColumn().Model(model).Column(column).Exec(ctx)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			_, err := tx.NewDropTable().Model((*models.Blob)(nil)).IfExists().Exec(ctx)
+
+			return err
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	})
+}
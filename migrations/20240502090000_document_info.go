@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"webapp-go/webapp/models"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [up migration] ")
+
+		for _, column := range []string{
+			"title text NOT NULL DEFAULT ''",
+			"language varchar(16) NOT NULL DEFAULT ''",
+			"page_count integer NOT NULL DEFAULT 0",
+			"word_count integer NOT NULL DEFAULT 0",
+			"index_status varchar(16) NOT NULL DEFAULT 'pending'",
+			"index_error text NOT NULL DEFAULT ''",
+		} {
+			_, err := db.NewAddColumn().
+				Model((*models.Document)(nil)).
+				ColumnExpr(column).
+				IfNotExists().
+				Exec(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		// The documents that already have embeddings were indexed, their title
+		// and counts are filled by the next reindex
+		_, err := db.ExecContext(ctx, `UPDATE "documents" d SET "index_status" = 'indexed'
+			WHERE "index_status" = 'pending' AND EXISTS (SELECT 1 FROM "document_embeddings" de WHERE de."document_id" = d."id")`)
+		if err != nil {
+			panic(err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		fmt.Print(" [down migration] ")
+
+		for _, column := range []string{"title", "language", "page_count", "word_count", "index_status", "index_error"} {
+			_, err := db.NewDropColumn().
+				Model((*models.Document)(nil)).
+				Column(column).
+				Exec(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -1,6 +1,9 @@
 package migrations
 
 import (
+	"context"
+
+	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/migrate"
 )
 
@@ -11,3 +14,33 @@ func init() {
 		panic(err)
 	}
 }
+
+// hasTable reports whether the table exists.
+func hasTable(ctx context.Context, db bun.IDB, table string) bool {
+	exists, err := db.NewSelect().
+		TableExpr("information_schema.tables").
+		Where("table_schema = current_schema()").
+		Where("table_name = ?", table).
+		Exists(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	return exists
+}
+
+// hasColumn reports whether the table has the column, for the migrations that
+// move data out of a column that the models no longer declare.
+func hasColumn(ctx context.Context, db bun.IDB, table string, column string) bool {
+	exists, err := db.NewSelect().
+		TableExpr("information_schema.columns").
+		Where("table_schema = current_schema()").
+		Where("table_name = ?", table).
+		Where("column_name = ?", column).
+		Exists(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	return exists
+}
@@ -0,0 +1,176 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrTooManyEntries = errors.New("archive has too many entries")
+	ErrTooLarge       = errors.New("archive is too large once expanded")
+	ErrUnsafePath     = errors.New("archive entry has an unsafe path")
+)
+
+// Limits bounds what is expanded from an archive, so that a crafted archive
+// cannot exhaust the memory or write outside of the post.
+type Limits struct {
+	MaxEntries   int
+	MaxTotalSize int64
+}
+
+// Entry is a regular file of an archive, named by its path in the archive.
+type Entry struct {
+	Path    string
+	Content []byte
+}
+
+// IsArchive reports whether a file with the detected content type is an
+// archive that is expanded on upload.
+func IsArchive(filename string, contentType string) bool {
+	switch contentType {
+	case "application/zip", "application/x-tar":
+		return true
+	case "application/gzip":
+		name := strings.ToLower(filename)
+		return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+	}
+
+	return false
+}
+
+// Expand returns the regular files of a zip, tar or gzipped tar archive.
+// Directories, links and the metadata files of the operating systems, such as
+// __MACOSX, are skipped.
+func Expand(contentType string, content []byte, limits Limits) ([]Entry, error) {
+	switch contentType {
+	case "application/zip":
+		return expandZip(content, limits)
+	case "application/x-tar":
+		return expandTar(bytes.NewReader(content), limits)
+	case "application/gzip":
+		r, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return expandTar(r, limits)
+	}
+
+	return nil, fmt.Errorf("not an archive: %s", contentType)
+}
+
+// entryPath returns the cleaned path of an entry, or false when the entry is
+// operating system metadata that is skipped.
+func entryPath(name string) (string, bool, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+
+	cleaned := path.Clean(name)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(name, "\x00") {
+		return "", false, fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	for _, part := range strings.Split(cleaned, "/") {
+		if part == "__MACOSX" || strings.HasPrefix(part, ".") {
+			return "", false, nil
+		}
+	}
+
+	return cleaned, true, nil
+}
+
+// expander keeps track of the limits while the entries are read.
+type expander struct {
+	limits  Limits
+	total   int64
+	entries []Entry
+}
+
+func (this *expander) add(name string, r io.Reader) error {
+	p, ok, err := entryPath(name)
+	if err != nil || !ok {
+		return err
+	}
+
+	if this.limits.MaxEntries > 0 && len(this.entries) >= this.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d", ErrTooManyEntries, this.limits.MaxEntries)
+	}
+
+	// The sizes declared in the archive are not trusted
+	if this.limits.MaxTotalSize > 0 {
+		r = io.LimitReader(r, this.limits.MaxTotalSize-this.total+1)
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	this.total += int64(len(content))
+	if this.limits.MaxTotalSize > 0 && this.total > this.limits.MaxTotalSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, this.limits.MaxTotalSize)
+	}
+
+	this.entries = append(this.entries, Entry{Path: p, Content: content})
+
+	return nil
+}
+
+func expandZip(content []byte, limits Limits) ([]Entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	e := expander{limits: limits}
+	for _, f := range archive.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.add(f.Name, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e.entries, nil
+}
+
+func expandTar(r io.Reader, limits Limits) ([]Entry, error) {
+	archive := tar.NewReader(r)
+
+	e := expander{limits: limits}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := e.add(header.Name, archive); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.entries, nil
+}
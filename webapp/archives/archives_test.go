@@ -0,0 +1,172 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type file struct {
+	name    string
+	content string
+	link    bool
+}
+
+func buildZip(t *testing.T, files []file) []byte {
+	buf := bytes.Buffer{}
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		header := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		if f.link {
+			header.SetMode(0o777 | os.ModeSymlink)
+		}
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func buildTar(t *testing.T, files []file) []byte {
+	buf := bytes.Buffer{}
+	w := tar.NewWriter(&buf)
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if f.link {
+			header = &tar.Header{Name: f.name, Linkname: f.content, Typeflag: tar.TypeSymlink}
+		} else if strings.HasSuffix(f.name, "/") {
+			header = &tar.Header{Name: f.name, Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func buildTarGz(t *testing.T, files []file) []byte {
+	buf := bytes.Buffer{}
+	w := gzip.NewWriter(&buf)
+	w.Write(buildTar(t, files))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		filename    string
+		contentType string
+		want        bool
+	}{
+		{filename: "notes.zip", contentType: "application/zip", want: true},
+		{filename: "notes.tar", contentType: "application/x-tar", want: true},
+		{filename: "notes.tar.gz", contentType: "application/gzip", want: true},
+		{filename: "NOTES.TGZ", contentType: "application/gzip", want: true},
+		{filename: "notes.txt.gz", contentType: "application/gzip", want: false},
+		{filename: "slides.docx", contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsArchive(tt.filename, tt.contentType); got != tt.want {
+			t.Errorf("IsArchive(%q, %q) = %v, want %v", tt.filename, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	files := []file{
+		{name: "notes/week1.md", content: "# Week 1"},
+		{name: "notes/", content: ""},
+		{name: "./notes/week2.md", content: "# Week 2"},
+		{name: "__MACOSX/notes/._week1.md", content: "metadata"},
+		{name: "notes/.DS_Store", content: "metadata"},
+		{name: "notes/latest.md", content: "week2.md", link: true},
+	}
+	want := []Entry{{Path: "notes/week1.md", Content: []byte("# Week 1")}, {Path: "notes/week2.md", Content: []byte("# Week 2")}}
+
+	archives := map[string][]byte{
+		"application/zip":   buildZip(t, files),
+		"application/x-tar": buildTar(t, files),
+		"application/gzip":  buildTarGz(t, files),
+	}
+
+	for contentType, content := range archives {
+		t.Run(contentType, func(t *testing.T) {
+			entries, err := Expand(contentType, content, Limits{MaxEntries: 10, MaxTotalSize: 1024})
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+
+			if len(entries) != len(want) {
+				t.Fatalf("Expand() = %d entries, want %d: %v", len(entries), len(want), entries)
+			}
+			for i := range want {
+				if entries[i].Path != want[i].Path || !bytes.Equal(entries[i].Content, want[i].Content) {
+					t.Errorf("Expand() entry %d = %s %q, want %s %q", i, entries[i].Path, entries[i].Content, want[i].Path, want[i].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []file
+		limits Limits
+		err    error
+	}{
+		{name: "within the limits", files: []file{{name: "a.md", content: "aaaa"}, {name: "b.md", content: "bbbb"}}, limits: Limits{MaxEntries: 2, MaxTotalSize: 8}},
+		{name: "no limits", files: []file{{name: "a.md", content: strings.Repeat("a", 4096)}}},
+		{name: "too many entries", files: []file{{name: "a.md", content: "a"}, {name: "b.md", content: "b"}, {name: "c.md", content: "c"}}, limits: Limits{MaxEntries: 2}, err: ErrTooManyEntries},
+		{name: "skipped entries do not count", files: []file{{name: "a.md", content: "a"}, {name: ".hidden", content: "h"}, {name: "b.md", content: "b"}}, limits: Limits{MaxEntries: 2}},
+		{name: "too large", files: []file{{name: "a.md", content: "aaaa"}, {name: "b.md", content: "bbbbb"}}, limits: Limits{MaxTotalSize: 8}, err: ErrTooLarge},
+		// Zeros are compressed to almost nothing, the size read is what counts
+		{name: "zip bomb", files: []file{{name: "a.md", content: strings.Repeat("\x00", 1<<20)}}, limits: Limits{MaxTotalSize: 1024}, err: ErrTooLarge},
+		{name: "parent directory", files: []file{{name: "../outside.md", content: "a"}}, err: ErrUnsafePath},
+		{name: "nested parent directory", files: []file{{name: "notes/../../outside.md", content: "a"}}, err: ErrUnsafePath},
+		{name: "absolute path", files: []file{{name: "/etc/passwd", content: "a"}}, err: ErrUnsafePath},
+		{name: "windows parent directory", files: []file{{name: `..\outside.md`, content: "a"}}, err: ErrUnsafePath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for contentType, content := range map[string][]byte{"application/zip": buildZip(t, tt.files), "application/x-tar": buildTar(t, tt.files)} {
+				_, err := Expand(contentType, content, tt.limits)
+				if tt.err == nil && err != nil {
+					t.Errorf("Expand(%s) error = %v, want nil", contentType, err)
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Errorf("Expand(%s) error = %v, want %v", contentType, err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandInvalid(t *testing.T) {
+	for _, contentType := range []string{"application/zip", "application/x-tar", "application/gzip", "text/plain"} {
+		if _, err := Expand(contentType, []byte("not an archive, but long enough to be read as one"), Limits{}); err == nil {
+			t.Errorf("Expand(%s) of garbage error = nil", contentType)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package blobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"webapp-go/webapp/config"
+
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore keeps the content of the documents out of their rows. The keys are
+// paths of word characters, dots and dashes, such as "<post>/<document>".
+type BlobStore interface {
+	Put(c context.Context, key string, content []byte) error
+	Get(c context.Context, key string) ([]byte, error)
+	Delete(c context.Context, key string) error
+}
+
+// Names of the backends of the configuration.
+const (
+	BACKEND_POSTGRES   = "postgres"
+	BACKEND_FILESYSTEM = "filesystem"
+	BACKEND_S3         = "s3"
+)
+
+// New returns the store of the backend, configured from the blobs section of
+// the configuration.
+func New(backend string, cfg config.Config, db *bun.DB) (BlobStore, error) {
+	switch backend {
+	case BACKEND_POSTGRES:
+		return NewPostgresStore(db), nil
+	case BACKEND_FILESYSTEM:
+		return NewFilesystemStore(cfg.Blobs.Filesystem.Root)
+	case BACKEND_S3:
+		return NewS3Store(cfg.Blobs.S3.Endpoint, cfg.Blobs.S3.Region, cfg.Blobs.S3.Bucket, cfg.Blobs.S3.Prefix, cfg.Blobs.S3.AccessKey, cfg.Blobs.S3.SecretKey)
+	}
+
+	return nil, fmt.Errorf("unknown blob backend: %s", backend)
+}
+
+var validKey = regexp.MustCompile(`^\w[\w.-]*(/\w[\w.-]*)*$`)
+
+// checkKey refuses the keys that could escape the directory or the bucket of
+// the store.
+func checkKey(key string) error {
+	if len(key) > 256 || !validKey.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package blobs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "0c1d/5e6f", valid: true},
+		{key: "post/document.v2", valid: true},
+		{key: "a-b_c", valid: true},
+		{key: ""},
+		{key: "/absolute"},
+		{key: "../outside"},
+		{key: "post/../outside"},
+		{key: "post/.hidden"},
+		{key: "post//document"},
+		{key: "post/"},
+		{key: `post\document`},
+		{key: "post/doc ument"},
+		{key: strings.Repeat("a", 257)},
+	}
+
+	for _, tt := range tests {
+		err := checkKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("checkKey(%q) = %v, want nil", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("checkKey(%q) = %v, want %v", tt.key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestFilesystemStore(t *testing.T) {
+	store, err := NewFilesystemStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+}
@@ -0,0 +1,90 @@
+package blobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type filesystemStore struct {
+	root string
+}
+
+// NewFilesystemStore keeps the blobs as files under the root directory, one
+// file per key.
+func NewFilesystemStore(root string) (BlobStore, error) {
+	if root == "" {
+		return nil, errors.New("the filesystem blob store needs a root directory")
+	}
+
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+
+	return filesystemStore{root}, nil
+}
+
+func (this filesystemStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(this.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file that is renamed once complete, so
+// that a blob is never read half written.
+func (this filesystemStore) Put(c context.Context, key string, content []byte) error {
+	p, err := this.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (this filesystemStore) Get(c context.Context, key string) ([]byte, error) {
+	p, err := this.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return content, err
+}
+
+func (this filesystemStore) Delete(c context.Context, key string) error {
+	p, err := this.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
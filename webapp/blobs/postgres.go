@@ -0,0 +1,51 @@
+package blobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"webapp-go/webapp/models"
+
+	"github.com/uptrace/bun"
+)
+
+type postgresStore struct {
+	db *bun.DB
+}
+
+// NewPostgresStore keeps the blobs in the blobs table, apart from the
+// documents so that listing them does not read their content.
+func NewPostgresStore(db *bun.DB) BlobStore {
+	return postgresStore{db}
+}
+
+func (this postgresStore) Put(c context.Context, key string, content []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	blob := models.Blob{Key: key, Content: content}
+	_, err := this.db.NewInsert().
+		Model(&blob).
+		On("CONFLICT (key) DO UPDATE").
+		Set("content = EXCLUDED.content").
+		Exec(c)
+
+	return err
+}
+
+func (this postgresStore) Get(c context.Context, key string) ([]byte, error) {
+	blob := models.Blob{}
+	err := this.db.NewSelect().Model(&blob).Where("key = ?", key).Scan(c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return blob.Content, err
+}
+
+func (this postgresStore) Delete(c context.Context, key string) error {
+	_, err := this.db.NewDelete().Model((*models.Blob)(nil)).Where("key = ?", key).Exec(c)
+
+	return err
+}
@@ -0,0 +1,213 @@
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type s3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Store keeps the blobs in a bucket of an S3 compatible service, such as
+// MinIO. The objects are addressed with path style URLs, which every service
+// supports, and the requests are signed with AWS signature version 4.
+func NewS3Store(endpoint string, region string, bucket string, prefix string, accessKey string, secretKey string) (BlobStore, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", endpoint)
+	}
+
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return nil, fmt.Errorf("invalid S3 bucket: %q", bucket)
+	}
+
+	if prefix != "" {
+		prefix = strings.Trim(prefix, "/")
+		if err := checkKey(prefix); err != nil {
+			return nil, err
+		}
+		prefix += "/"
+	}
+
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return s3Store{u, region, bucket, prefix, accessKey, secretKey, &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+func (this s3Store) do(c context.Context, method string, key string, body []byte) (*http.Response, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	u := *this.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + this.bucket + "/" + this.prefix + key
+
+	req, err := http.NewRequestWithContext(c, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	signS3(req, body, this.region, this.accessKey, this.secretKey, time.Now())
+
+	return this.client.Do(req)
+}
+
+// s3Error reads the error returned by the service.
+func s3Error(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+
+	return fmt.Errorf("s3: %s %s: %s: %s", res.Request.Method, res.Request.URL.Path, res.Status, strings.TrimSpace(string(body)))
+}
+
+func (this s3Store) Put(c context.Context, key string, content []byte) error {
+	res, err := this.do(c, http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func (this s3Store) Get(c context.Context, key string) ([]byte, error) {
+	res, err := this.do(c, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, s3Error(res)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func (this s3Store) Delete(c context.Context, key string) error {
+	res, err := this.do(c, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// s3Escape encodes a path as S3 expects it in the canonical request, every
+// byte but the unreserved characters and the slashes being escaped.
+func s3Escape(p string) string {
+	escaped := strings.Builder{}
+	for _, b := range []byte(p) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-_.~/", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
+
+// signS3 adds the AWS signature version 4 of the request to its headers. The
+// host, the date, the payload hash and the headers already set are signed.
+func signS3(req *http.Request, body []byte, region string, accessKey string, secretKey string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := strings.Builder{}
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			params = append(params, s3Escape(k)+"="+strings.ReplaceAll(s3Escape(v), "/", "%2F"))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path),
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
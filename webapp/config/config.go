@@ -1,6 +1,10 @@
 package config
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 type Config struct {
 	ConfigPath string `env:"CONFIG_PATH" env-default:"config.yaml"`
@@ -32,6 +36,54 @@ type Config struct {
 		Url   string `yaml:"url"`
 		Model string `yaml:"model"`
 	} `yaml:"ollama"`
+	Embeddings struct {
+		ChunkSize    int           `yaml:"chunkSize" env-default:"2000"`
+		BatchSize    int           `yaml:"batchSize" env-default:"16"`
+		BatchLatency time.Duration `yaml:"batchLatency" env-default:"2s"`
+		// DuplicateThreshold is the similarity from which an indexed document
+		// is reported as a near duplicate of another one of its post
+		DuplicateThreshold float32 `yaml:"duplicateThreshold" env-default:"0.95"`
+	} `yaml:"embeddings"`
+	SearchCache struct {
+		Enabled bool          `yaml:"enabled" env-default:"true"`
+		TTL     time.Duration `yaml:"ttl" env-default:"24h"`
+	} `yaml:"searchCache"`
+	Uploads struct {
+		AllowedContentTypes []string `yaml:"allowedContentTypes" env:"UPLOADS_ALLOWED_CONTENT_TYPES" env-separator:","`
+		MaxArchiveEntries   int      `yaml:"maxArchiveEntries" env-default:"500"`
+		MaxArchiveSize      int64    `yaml:"maxArchiveSize" env-default:"268435456"`
+	} `yaml:"uploads"`
+	Import struct {
+		Timeout              time.Duration `yaml:"timeout" env-default:"15s"`
+		MaxSize              int64         `yaml:"maxSize" env-default:"10485760"`
+		AllowPrivateNetworks bool          `yaml:"allowPrivateNetworks" env-default:"false"`
+	} `yaml:"import"`
+	Git struct {
+		CacheDir    string        `yaml:"cacheDir" env-default:"/tmp/webapp-git"`
+		Timeout     time.Duration `yaml:"timeout" env-default:"5m"`
+		MaxFileSize int64         `yaml:"maxFileSize" env-default:"10485760"`
+		AllowLocal  bool          `yaml:"allowLocal" env-default:"false"`
+		// WebhookQueueSize bounds the push deliveries waiting to be synced
+		WebhookQueueSize int           `yaml:"webhookQueueSize" env-default:"32"`
+		WebhookTimeout   time.Duration `yaml:"webhookTimeout" env-default:"10m"`
+	} `yaml:"git"`
+	Notebooks struct {
+		IncludeOutputs bool `yaml:"includeOutputs" env-default:"false"`
+	} `yaml:"notebooks"`
+	Blobs struct {
+		Backend    string `yaml:"backend" env:"BLOBS_BACKEND" env-default:"postgres"`
+		Filesystem struct {
+			Root string `yaml:"root" env-default:"/var/lib/webapp/blobs"`
+		} `yaml:"filesystem"`
+		S3 struct {
+			Endpoint  string `yaml:"endpoint"`
+			Region    string `yaml:"region" env-default:"us-east-1"`
+			Bucket    string `yaml:"bucket"`
+			Prefix    string `yaml:"prefix"`
+			AccessKey string `yaml:"accessKey" env:"BLOBS_S3_ACCESS_KEY"`
+			SecretKey string `yaml:"secretKey" env:"BLOBS_S3_SECRET_KEY"`
+		} `yaml:"s3"`
+	} `yaml:"blobs"`
 }
 
 func LoadConfig() (cfg Config, err error) {
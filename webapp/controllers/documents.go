@@ -1,12 +1,27 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path"
+	"strings"
+	"time"
+	"webapp-go/webapp/archives"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/diff"
 	"webapp-go/webapp/middlewares"
 	"webapp-go/webapp/models"
+	"webapp-go/webapp/parsers"
 	"webapp-go/webapp/repositories"
+	"webapp-go/webapp/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -15,42 +30,153 @@ import (
 type DocumentsController interface {
 	GetDocument(c *gin.Context)
 	GetDocuments(c *gin.Context)
+	GetDocumentRaw(c *gin.Context)
 	CreateDocument(c *gin.Context)
+	ImportDocument(c *gin.Context)
 	UpdateDocument(c *gin.Context)
 	DeleteDocument(c *gin.Context)
+	GetRevisions(c *gin.Context)
+	DiffRevisions(c *gin.Context)
+	RestoreRevision(c *gin.Context)
 }
 
 type documentsController struct {
-	documentsRepo repositories.DocumentsRepository
-	postsRepo     repositories.PostsRepository
-	documentChan  chan<- models.DocumentChanItem
+	cfg               config.Config
+	documentsRepo     repositories.DocumentsRepository
+	postsRepo         repositories.PostsRepository
+	searchCacheRepo   repositories.SearchCacheRepository
+	importService     services.ImportService
+	embeddingsService services.EmbeddingsService
+	documentChan      chan<- models.DocumentChanItem
 }
 
-func NewDocumentsController(documentsRepo repositories.DocumentsRepository, postsRepo repositories.PostsRepository, documentChan chan<- models.DocumentChanItem) DocumentsController {
-	return documentsController{documentsRepo, postsRepo, documentChan}
+func NewDocumentsController(cfg config.Config, documentsRepo repositories.DocumentsRepository, postsRepo repositories.PostsRepository, searchCacheRepo repositories.SearchCacheRepository, importService services.ImportService, embeddingsService services.EmbeddingsService, documentChan chan<- models.DocumentChanItem) DocumentsController {
+	return documentsController{cfg, documentsRepo, postsRepo, searchCacheRepo, importService, embeddingsService, documentChan}
+}
+
+// allowedContentType reports whether documents with the content type can be
+// uploaded. Every type that can be parsed is allowed when the deployment does
+// not restrict them.
+func (this documentsController) allowedContentType(contentType string) bool {
+	allowed := this.cfg.Uploads.AllowedContentTypes
+	if len(allowed) > 0 && !parsers.MatchContentType(contentType, allowed) {
+		return false
+	}
+
+	return parsers.Supported("", contentType)
+}
+
+func (this documentsController) archiveLimits() archives.Limits {
+	return archives.Limits{MaxEntries: this.cfg.Uploads.MaxArchiveEntries, MaxTotalSize: this.cfg.Uploads.MaxArchiveSize}
+}
+
+// invalidateSearchCache bumps the document version of the post, so that the
+// cached answers no longer match, and drops the cached answers of the post.
+func (this documentsController) invalidateSearchCache(c context.Context, slug uuid.UUID) {
+	if _, err := this.postsRepo.BumpDocumentVersion(c, slug); err != nil {
+		slog.Error("Error bumping the document version for post with slug", "slug", slug, "error", err.Error())
+	}
+
+	if _, err := this.searchCacheRepo.DeleteEntriesFor(c, slug); err != nil {
+		slog.Error("Error invalidating the search cache for post with slug", "slug", slug, "error", err.Error())
+	}
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
 }
 
 type DocumentGetQuery struct {
-	Slug uuid.UUID `form:"slug" binding:"required"`
-	ID   uuid.UUID `form:"id" binding:"required"`
+	Slug string `uri:"slug" binding:"required,uuid"`
+	ID   string `uri:"id" binding:"required,uuid"`
 }
 
 func (this documentsController) GetDocument(c *gin.Context) {
 	query := DocumentGetQuery{}
-	if err := c.ShouldBind(&query); err != nil {
+	if err := c.ShouldBindUri(&query); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
-	document, err := this.documentsRepo.GetDocument(c, query.Slug, query.ID)
+	document, err := this.documentsRepo.GetDocument(c, uuid.MustParse(query.Slug), uuid.MustParse(query.ID))
 	if err != nil {
 		c.Status(http.StatusNotFound)
 		return
 	}
 
+	document.Content, err = this.documentsRepo.GetContent(c, document)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, document)
 }
 
+type DocumentRawQuery struct {
+	Slug string `uri:"slug" binding:"required,uuid"`
+	ID   string `uri:"id" binding:"required,uuid"`
+}
+
+// etagMatches reports whether an If-None-Match header lists the entity tag.
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetDocumentRaw sends the original file of the document. The hash of the
+// content is its entity tag, so that clients can revalidate without
+// downloading it again, and ranges are served for the large files.
+func (this documentsController) GetDocumentRaw(c *gin.Context) {
+	query := DocumentRawQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	document, err := this.documentsRepo.GetDocument(c, uuid.MustParse(query.Slug), uuid.MustParse(query.ID))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-cache")
+	if document.ContentHash != "" {
+		etag := `"` + document.ContentHash + `"`
+		c.Header("ETag", etag)
+
+		// The content is not read for the clients that already have it
+		if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	content, err := this.documentsRepo.GetContent(c, document)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("Content-Type", document.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(document.Filename)}))
+
+	// The documents change in place, so only the entity tag tells their version
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, bytes.NewReader(content))
+}
+
 type DocumentsGetQuery struct {
 	Slug string `uri:"slug" binding:"required,uuid"`
 }
@@ -72,7 +198,8 @@ func (this documentsController) GetDocuments(c *gin.Context) {
 }
 
 type DocumentCreateQuery struct {
-	Slug string `uri:"slug" binding:"required,uuid"`
+	Slug             string `uri:"slug" binding:"required,uuid"`
+	RejectDuplicates bool   `form:"rejectDuplicates"`
 }
 
 func (this documentsController) CreateDocument(c *gin.Context) {
@@ -83,6 +210,10 @@ func (this documentsController) CreateDocument(c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
 
 	post, err := this.postsRepo.GetPost(c, uuid.MustParse(query.Slug))
 	if err != nil {
@@ -98,34 +229,155 @@ func (this documentsController) CreateDocument(c *gin.Context) {
 	form, _ := c.MultipartForm()
 	files := form.File["file"]
 
-	documents := make([]models.Document, 0)
-	for _, file := range files {
-		f, err := file.Open()
-		if err != nil {
-			log.Printf("Could not open file %s", file.Filename)
-			continue
-		}
+	existing := map[string]bool{}
+	for _, d := range post.Documents {
+		existing[d.Filename] = true
+	}
 
-		defer f.Close()
+	// Every file is parsed before anything is stored, so that the uploader
+	// learns about the unsupported files instead of them being indexed empty
+	pending := make([]models.Document, 0)
+	unsupported := make([]string, 0)
+	conflicts := make([]string, 0)
+
+	add := func(filename string, content []byte, skipUnsupported bool) {
+		// Browsers send application/octet-stream for the types they do not
+		// know, so the content type is detected instead of trusting the header
+		contentType := parsers.DetectContentType(filename, content)
+		if !this.allowedContentType(contentType) {
+			if skipUnsupported {
+				slog.Info("Skipping unsupported archive entry", "filename", filename, "contentType", contentType)
+			} else {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", filename, contentType))
+			}
+			return
+		}
 
-		content, err := io.ReadAll(f)
-		if err != nil {
-			log.Printf("Could not read file %s", file.Filename)
-			continue
+		if existing[filename] {
+			conflicts = append(conflicts, filename)
+			return
 		}
+		existing[filename] = true
 
 		document := models.NewDocument(
 			models.DocumentDTO{
-				Filename:    file.Filename,
-				ContentType: file.Header.Get("Content-Type"),
+				Filename:    filename,
+				ContentType: contentType,
 				Content:     content,
 				PostSlug:    post.Slug,
 			},
 		)
 
-		document, err = this.documentsRepo.CreateDocument(c, document)
+		parsed, err := document.Parse()
+		if err != nil {
+			slog.Warn("Could not parse file", "filename", filename, "error", err.Error())
+		}
+		document.Describe(parsed)
+
+		// The id is set before the insert, so that the warnings about the
+		// duplicates within the request can point to the document
+		document.ID = uuid.New()
+
+		pending = append(pending, document)
+	}
+
+	for _, file := range files {
+		content, err := readFormFile(file)
 		if err != nil {
-			log.Printf("Could not create document entry for file %s: %s", file.Filename, err.Error())
+			slog.Error("Could not read file", "filename", file.Filename, "error", err.Error())
+			continue
+		}
+
+		contentType := parsers.DetectContentType(file.Filename, content)
+		if !archives.IsArchive(file.Filename, contentType) {
+			add(file.Filename, content, false)
+			continue
+		}
+
+		allowed := this.cfg.Uploads.AllowedContentTypes
+		if len(allowed) > 0 && !parsers.MatchContentType(contentType, allowed) {
+			unsupported = append(unsupported, fmt.Sprintf("%s (%s)", file.Filename, contentType))
+			continue
+		}
+
+		// Archives are expanded in one document per file, the entries that
+		// cannot be indexed, such as images, are skipped
+		entries, err := archives.Expand(contentType, content, this.archiveLimits())
+		if errors.Is(err, archives.ErrTooManyEntries) || errors.Is(err, archives.ErrTooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "files": []string{file.Filename}})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "files": []string{file.Filename}})
+			return
+		}
+
+		for _, entry := range entries {
+			if len(entry.Path) > models.DocumentFilenameMaxLength {
+				slog.Info("Skipping archive entry with a path too long", "filename", entry.Path)
+				continue
+			}
+
+			add(entry.Path, entry.Content, true)
+		}
+	}
+
+	if len(unsupported) > 0 {
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content type", "files": unsupported})
+		return
+	}
+
+	if len(conflicts) > 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Documents already exist", "files": conflicts})
+		return
+	}
+
+	// The near duplicates are found from the embeddings of the chunks, the
+	// worker looks for them again once the documents are indexed. The upload
+	// goes on with the copies only when the embeddings cannot be generated.
+	nearDuplicates, err := this.embeddingsService.NearDuplicates(c, post.Slug, pending)
+	if err != nil {
+		slog.Warn("Could not look for near duplicates of the uploaded files", "slug", post.Slug, "error", err.Error())
+	}
+
+	duplicates := map[string][]models.DocumentWarning{}
+	for i := range pending {
+		pending[i].Warnings = duplicateWarnings(post.Documents, pending[:i], pending[i])
+
+		copies := map[uuid.UUID]bool{}
+		for _, w := range pending[i].Warnings {
+			copies[w.DocumentID] = true
+		}
+		if i < len(nearDuplicates) {
+			for _, w := range nearDuplicates[i] {
+				if !copies[w.DocumentID] {
+					pending[i].Warnings = append(pending[i].Warnings, w)
+				}
+			}
+		}
+
+		if len(pending[i].Warnings) > 0 {
+			duplicates[pending[i].Filename] = pending[i].Warnings
+		}
+	}
+
+	if query.RejectDuplicates && len(duplicates) > 0 {
+		files := make([]string, 0, len(duplicates))
+		for _, p := range pending {
+			if _, ok := duplicates[p.Filename]; ok {
+				files = append(files, p.Filename)
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Duplicate documents", "files": files, "duplicates": duplicates})
+		return
+	}
+
+	documents := make([]models.Document, 0)
+	for _, p := range pending {
+		document, err := this.documentsRepo.CreateDocument(c, p, userId)
+		if err != nil {
+			slog.Error("Could not create document entry for file", "filename", p.Filename, "error", err.Error())
 			continue
 		}
 
@@ -134,9 +386,110 @@ func (this documentsController) CreateDocument(c *gin.Context) {
 		documents = append(documents, document)
 	}
 
+	if len(documents) > 0 {
+		this.invalidateSearchCache(c, post.Slug)
+	}
+
 	c.JSON(http.StatusOK, documents)
 }
 
+// duplicateWarnings lists the documents of the post, then the files uploaded
+// before it in the same request, with the same content as the uploaded
+// document.
+func duplicateWarnings(existing []*models.Document, uploaded []models.Document, document models.Document) []models.DocumentWarning {
+	warnings := []models.DocumentWarning{}
+
+	hash := models.ContentHash(document.Content)
+	for _, d := range existing {
+		if d.ContentHash == hash {
+			warnings = append(warnings, models.DocumentWarning{Kind: models.WARNING_DUPLICATE, DocumentID: d.ID, Filename: d.Filename, Similarity: 1})
+		}
+	}
+	for _, d := range uploaded {
+		if models.ContentHash(d.Content) == hash {
+			warnings = append(warnings, models.DocumentWarning{Kind: models.WARNING_DUPLICATE, DocumentID: d.ID, Filename: d.Filename, Similarity: 1})
+		}
+	}
+
+	return warnings
+}
+
+type DocumentImportQuery struct {
+	Slug string `uri:"slug" binding:"required,uuid"`
+}
+
+func (this documentsController) ImportDocument(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := DocumentImportQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var dto models.DocumentImportDTO
+	if err := c.ShouldBind(&dto); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := this.postsRepo.GetPost(c, uuid.MustParse(query.Slug))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if post.AuthorID != userId {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	document, err := this.importService.Import(c, dto.Url)
+	switch {
+	case errors.Is(err, services.ErrImportForbidden):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrImportTooLarge):
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, parsers.ErrUnsupported):
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dto.Filename != "" {
+		document.Filename = dto.Filename
+	}
+	document.PostSlug = post.Slug
+
+	if !this.allowedContentType(document.ContentType) {
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content type", "files": []string{fmt.Sprintf("%s (%s)", document.Filename, document.ContentType)}})
+		return
+	}
+
+	for _, d := range post.Documents {
+		if d.Filename == document.Filename {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Documents already exist", "files": []string{document.Filename}})
+			return
+		}
+	}
+
+	document, err = this.documentsRepo.CreateDocument(c, document, userId)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	this.invalidateSearchCache(c, post.Slug)
+
+	this.documentChan <- models.NewDocumentChanItem(models.CREATE, document.PostSlug, document.ID)
+
+	c.JSON(http.StatusOK, document)
+}
+
 type DocumentUpdateQuery struct {
 	Slug string `uri:"slug" binding:"required,uuid"`
 	ID   string `uri:"id" binding:"required,uuid"`
@@ -162,18 +515,108 @@ func (this documentsController) UpdateDocument(c *gin.Context) {
 		return
 	}
 
+	// A new version of the file can be uploaded in place of the JSON body, the
+	// document keeps its id, and its filename unless the type of the file
+	// changes, so that its citations stay the same
 	var dto models.DocumentDTO
-	if err := c.ShouldBind(&dto); err != nil {
+	uploaded := ""
+	if file, err := c.FormFile("file"); err == nil {
+		content, err := readFormFile(file)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if len(content) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "File is empty", "files": []string{file.Filename}})
+			return
+		}
+
+		dto.Filename = c.PostForm("filename")
+		dto.Content = content
+		uploaded = file.Filename
+	} else if err := c.ShouldBind(&dto); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
-	document, err := this.documentsRepo.UpdateDocument(c, post.Slug, uuid.MustParse(query.ID), models.NewDocument(dto))
+	update := models.NewDocument(dto)
+
+	existing, err := this.documentsRepo.GetDocument(c, post.Slug, uuid.MustParse(query.ID))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// The metadata is extracted again when the content changes
+	if len(update.Content) > 0 {
+		parsed := update
+		if parsed.Filename == "" {
+			parsed.Filename = existing.Filename
+		}
+
+		// The extension of the uploaded file tells its type better than the
+		// one of the document, such as a docx replacing a pdf
+		detectName := parsed.Filename
+		if uploaded != "" {
+			detectName = uploaded
+		}
+		parsed.ContentType = parsers.DetectContentType(detectName, parsed.Content)
+
+		// Text edited in the post page is detected as plain text, which must
+		// not turn a markdown document into a plain one
+		if uploaded == "" && parsed.ContentType == "text/plain" && strings.HasPrefix(existing.ContentType, "text/") {
+			parsed.ContentType = existing.ContentType
+		}
+
+		// A file of another type renames the document after the uploaded file,
+		// so that its extension, its download and its parser agree with it
+		if uploaded != "" && update.Filename == "" && parsed.ContentType != existing.ContentType {
+			parsed.Filename = path.Base(uploaded)
+			update.Filename = parsed.Filename
+		}
+
+		if extensionType, ok := parsers.ExtensionContentType(parsed.Filename); ok && uploaded != "" && extensionType != parsed.ContentType {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Filename does not match the content type", "files": []string{fmt.Sprintf("%s (%s)", parsed.Filename, parsed.ContentType)}})
+			return
+		}
+
+		if !this.allowedContentType(parsed.ContentType) {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content type", "files": []string{fmt.Sprintf("%s (%s)", parsed.Filename, parsed.ContentType)}})
+			return
+		}
+		update.ContentType = parsed.ContentType
+
+		content, err := parsed.Parse()
+		if err != nil {
+			slog.Warn("Could not parse document", "id", existing.ID, "error", err.Error())
+		}
+		update.Describe(content)
+	}
+
+	if update.Filename != "" && update.Filename != existing.Filename {
+		if len(update.Filename) > models.DocumentFilenameMaxLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Filename is too long", "files": []string{update.Filename}})
+			return
+		}
+
+		if filenameTaken(post, existing.ID, update.Filename) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Documents already exist", "files": []string{update.Filename}})
+			return
+		}
+	}
+
+	document, err := this.documentsRepo.UpdateDocument(c, post.Slug, uuid.MustParse(query.ID), update, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.Status(http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
+	this.invalidateSearchCache(c, post.Slug)
+
 	this.documentChan <- models.NewDocumentChanItem(models.UPDATE, document.PostSlug, document.ID)
 
 	c.JSON(http.StatusOK, document)
@@ -210,7 +653,200 @@ func (this documentsController) DeleteDocument(c *gin.Context) {
 		return
 	}
 
+	this.invalidateSearchCache(c, post.Slug)
+
 	this.documentChan <- models.NewDocumentChanItem(models.DELETE, post.Slug, uuid.MustParse(query.ID))
 
 	c.Status(http.StatusNoContent)
 }
+
+type DocumentRevisionsQuery struct {
+	Slug string `uri:"slug" binding:"required,uuid"`
+	ID   string `uri:"id" binding:"required,uuid"`
+}
+
+// authorizedDocument returns the post and the id of the document of the
+// request when the user is the author of the post.
+// filenameTaken returns true when another document of the post has the filename.
+func filenameTaken(post models.Post, id uuid.UUID, filename string) bool {
+	for _, d := range post.Documents {
+		if d.ID != id && d.Filename == filename {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (this documentsController) authorizedDocument(c *gin.Context) (models.Post, uuid.UUID, bool) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := DocumentRevisionsQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return models.Post{}, uuid.Nil, false
+	}
+
+	post, err := this.postsRepo.GetPost(c, uuid.MustParse(query.Slug))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return models.Post{}, uuid.Nil, false
+	}
+
+	if post.AuthorID != userId {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return models.Post{}, uuid.Nil, false
+	}
+
+	return post, uuid.MustParse(query.ID), true
+}
+
+func (this documentsController) GetRevisions(c *gin.Context) {
+	post, id, ok := this.authorizedDocument(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := this.documentsRepo.GetRevisions(c, post.Slug, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// revisionText returns the text that is compared between revisions, which is
+// the extracted text for the binary formats.
+func revisionText(revision models.DocumentRevision) (string, error) {
+	if strings.HasPrefix(revision.ContentType, "text/") {
+		return string(revision.Content), nil
+	}
+
+	parsed, err := parsers.Parse(revision.Filename, revision.ContentType, revision.Content)
+
+	return parsed.Text(), err
+}
+
+func (this documentsController) DiffRevisions(c *gin.Context) {
+	post, id, ok := this.authorizedDocument(c)
+	if !ok {
+		return
+	}
+
+	query := models.DocumentDiffQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	from, err := this.documentsRepo.GetRevision(c, post.Slug, id, query.From)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var to models.DocumentRevision
+	if query.To != uuid.Nil {
+		to, err = this.documentsRepo.GetRevision(c, post.Slug, id, query.To)
+	} else {
+		var document models.Document
+		document, err = this.documentsRepo.GetDocument(c, post.Slug, id)
+		if err == nil {
+			document.Content, err = this.documentsRepo.GetContent(c, document)
+		}
+		to = models.NewDocumentRevision(document, uuid.Nil)
+	}
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	fromText, err := revisionText(from)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	toText, err := revisionText(to)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := models.DocumentDiff{
+		From: from,
+		To:   to,
+		Diff: diff.Unified(from.Filename+"@"+from.CreatedAt.Format(time.RFC3339), to.Filename+"@"+to.CreatedAt.Format(time.RFC3339), fromText, toText, 3),
+	}
+	result.From.Content = nil
+	result.To.Content = nil
+
+	c.JSON(http.StatusOK, result)
+}
+
+type DocumentRestoreQuery struct {
+	Slug       string `uri:"slug" binding:"required,uuid"`
+	ID         string `uri:"id" binding:"required,uuid"`
+	RevisionID string `uri:"revisionId" binding:"required,uuid"`
+}
+
+// RestoreRevision brings back the content of a revision, which is recorded as
+// a new revision, and embeds the document again.
+func (this documentsController) RestoreRevision(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	post, id, ok := this.authorizedDocument(c)
+	if !ok {
+		return
+	}
+
+	query := DocumentRestoreQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	revision, err := this.documentsRepo.GetRevision(c, post.Slug, id, uuid.MustParse(query.RevisionID))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// The filename of the revision may have been taken by another document
+	// since the rename
+	if filenameTaken(post, id, revision.Filename) {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Documents already exist", "files": []string{revision.Filename}})
+		return
+	}
+
+	// The content hash is given so that an empty content is restored too
+	update := models.Document{Filename: revision.Filename, ContentType: revision.ContentType, Content: revision.Content, ContentHash: revision.ContentHash}
+
+	content, err := update.Parse()
+	if err != nil {
+		slog.Warn("Could not parse document", "id", id, "error", err.Error())
+	}
+	update.Describe(content)
+
+	document, err := this.documentsRepo.UpdateDocument(c, post.Slug, id, update, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	this.invalidateSearchCache(c, post.Slug)
+
+	this.documentChan <- models.NewDocumentChanItem(models.UPDATE, document.PostSlug, document.ID)
+
+	c.JSON(http.StatusOK, document)
+}
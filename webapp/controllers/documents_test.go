@@ -0,0 +1,509 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type fakeDocuments struct {
+	repositories.DocumentsRepository
+	documents []models.Document
+	revisions []models.DocumentRevision
+	updates   []models.Document
+}
+
+func (this *fakeDocuments) GetDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (models.Document, error) {
+	for _, d := range this.documents {
+		if d.PostSlug == slug && d.ID == id {
+			return d, nil
+		}
+	}
+	return models.Document{}, errors.New("no rows in result set")
+}
+
+func (this *fakeDocuments) GetContent(c context.Context, document models.Document) ([]byte, error) {
+	return document.Content, nil
+}
+
+func (this *fakeDocuments) CreateDocument(c context.Context, document models.Document, authorId uuid.UUID) (models.Document, error) {
+	document.ContentHash = models.ContentHash(document.Content)
+	this.documents = append(this.documents, document)
+	return document, nil
+}
+
+func (this *fakeDocuments) UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document, authorId uuid.UUID) (models.Document, error) {
+	for _, d := range this.documents {
+		if d.PostSlug == slug && d.ID == id {
+			this.updates = append(this.updates, document)
+
+			// The zero fields are left as they are
+			document.ID, document.PostSlug = id, slug
+			if document.Filename == "" {
+				document.Filename = d.Filename
+			}
+			if document.ContentType == "" {
+				document.ContentType = d.ContentType
+			}
+			return document, nil
+		}
+	}
+	return models.Document{}, sql.ErrNoRows
+}
+
+func (this *fakeDocuments) GetRevision(c context.Context, slug uuid.UUID, id uuid.UUID, revisionId uuid.UUID) (models.DocumentRevision, error) {
+	for _, r := range this.revisions {
+		if r.PostSlug == slug && r.DocumentID == id && r.ID == revisionId {
+			return r, nil
+		}
+	}
+	return models.DocumentRevision{}, sql.ErrNoRows
+}
+
+func (fakePosts) BumpDocumentVersion(c context.Context, slug uuid.UUID) (int, error) {
+	return 1, nil
+}
+
+type fakeSearchCache struct {
+	repositories.SearchCacheRepository
+}
+
+func (fakeSearchCache) DeleteEntriesFor(c context.Context, slug uuid.UUID) (uuid.UUID, error) {
+	return slug, nil
+}
+
+// uploadRequest builds a multipart request with the files, by name.
+func uploadRequest(t *testing.T, target string, files [][2]string) *http.Request {
+	body := bytes.Buffer{}
+	w := multipart.NewWriter(&body)
+	for _, f := range files {
+		part, err := w.CreateFormFile("file", f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(f[1]))
+	}
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return req
+}
+
+func TestCreateDocumentDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userId := uuid.New()
+	existing := &models.Document{ID: uuid.New(), Filename: "week1.md", ContentHash: models.ContentHash([]byte("# Week 1\n\nRecursion."))}
+	post := models.Post{Slug: uuid.New(), AuthorID: userId, Documents: []*models.Document{existing}}
+
+	tests := []struct {
+		name       string
+		query      string
+		files      [][2]string
+		near       map[string]string
+		nearErr    error
+		status     int
+		duplicates map[string][]string
+	}{
+		{
+			name:       "distinct files",
+			files:      [][2]string{{"week2.md", "# Week 2\n\nTrees."}, {"week3.md", "# Week 3\n\nGraphs."}},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{},
+		},
+		{
+			name:       "copy of a document",
+			files:      [][2]string{{"copy.md", "# Week 1\n\nRecursion."}},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"copy.md": {"week1.md"}},
+		},
+		{
+			name:       "copies in the same upload",
+			files:      [][2]string{{"week2.md", "# Week 2\n\nTrees."}, {"trees.md", "# Week 2\n\nTrees."}},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"trees.md": {"week2.md"}},
+		},
+		{
+			name:   "rejected copies",
+			query:  "?rejectDuplicates=true",
+			files:  [][2]string{{"week2.md", "# Week 2\n\nTrees."}, {"trees.md", "# Week 2\n\nTrees."}},
+			status: http.StatusConflict,
+		},
+		{
+			name:       "near duplicate of a document",
+			files:      [][2]string{{"recursion.md", "# Recursion\n\nWeek 1."}},
+			near:       map[string]string{"recursion.md": "week1.md"},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"recursion.md": {"near:week1.md"}},
+		},
+		{
+			name:       "near duplicates in the same upload",
+			files:      [][2]string{{"week2.md", "# Week 2\n\nTrees."}, {"trees.md", "# Trees\n\nWeek 2."}},
+			near:       map[string]string{"trees.md": "week2.md"},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"trees.md": {"near:week2.md"}},
+		},
+		{
+			name:       "copy reported once",
+			files:      [][2]string{{"copy.md", "# Week 1\n\nRecursion."}},
+			near:       map[string]string{"copy.md": "week1.md"},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"copy.md": {"week1.md"}},
+		},
+		{
+			name:   "rejected near duplicates",
+			query:  "?rejectDuplicates=true",
+			files:  [][2]string{{"recursion.md", "# Recursion\n\nWeek 1."}},
+			near:   map[string]string{"recursion.md": "week1.md"},
+			status: http.StatusConflict,
+		},
+		{
+			name:       "embeddings unavailable",
+			files:      [][2]string{{"copy.md", "# Week 1\n\nRecursion."}, {"recursion.md", "# Recursion\n\nWeek 1."}},
+			near:       map[string]string{"recursion.md": "week1.md"},
+			nearErr:    errors.New("connection refused"),
+			status:     http.StatusOK,
+			duplicates: map[string][]string{"copy.md": {"week1.md"}},
+		},
+		{
+			name:       "rejected without copies",
+			query:      "?rejectDuplicates=true",
+			files:      [][2]string{{"week2.md", "# Week 2\n\nTrees."}},
+			status:     http.StatusOK,
+			duplicates: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents := &fakeDocuments{}
+			documentChan := make(chan models.DocumentChanItem, len(tt.files))
+			embeddings := fakeEmbeddingsService{existing: post.Documents, near: tt.near, err: tt.nearErr}
+			controller := NewDocumentsController(config.Config{}, documents, fakePosts{post: post}, fakeSearchCache{}, nil, embeddings, documentChan)
+
+			router := gin.New()
+			router.POST("/api/posts/:slug/documents", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, userId) }, controller.CreateDocument)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, uploadRequest(t, "/api/posts/"+post.Slug.String()+"/documents"+tt.query, tt.files))
+
+			if w.Code != tt.status {
+				t.Fatalf("CreateDocument() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				if len(documents.documents) != 0 {
+					t.Errorf("CreateDocument() stored %d documents, want none", len(documents.documents))
+				}
+				return
+			}
+
+			created := []models.Document{}
+			if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+			if len(created) != len(tt.files) || len(documentChan) != len(tt.files) {
+				t.Fatalf("CreateDocument() created %d documents, queued %d, want %d", len(created), len(documentChan), len(tt.files))
+			}
+
+			ids := map[string]uuid.UUID{existing.Filename: existing.ID}
+			for _, d := range created {
+				ids[d.Filename] = d.ID
+			}
+
+			for _, d := range created {
+				want := tt.duplicates[d.Filename]
+				if len(d.Warnings) != len(want) {
+					t.Fatalf("CreateDocument() warnings of %s = %+v, want %v", d.Filename, d.Warnings, want)
+				}
+				for i, w := range d.Warnings {
+					kind, filename := models.WARNING_DUPLICATE, want[i]
+					if near, ok := strings.CutPrefix(want[i], "near:"); ok {
+						kind, filename = models.WARNING_NEAR_DUPLICATE, near
+					}
+					if w.Kind != kind || w.Filename != filename || w.DocumentID != ids[filename] {
+						t.Errorf("CreateDocument() warning of %s = %+v, want %s", d.Filename, w, want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	document := models.Document{ID: uuid.New(), PostSlug: uuid.New(), Filename: "notes.md", ContentType: "text/markdown", Content: []byte("# Notes")}
+	controller := NewDocumentsController(config.Config{}, &fakeDocuments{documents: []models.Document{document}}, nil, nil, nil, nil, nil)
+
+	router := gin.New()
+	router.GET("/api/posts/:slug/documents/:id", controller.GetDocument)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "found", path: "/api/posts/" + document.PostSlug.String() + "/documents/" + document.ID.String(), status: http.StatusOK},
+		{name: "other post", path: "/api/posts/" + uuid.NewString() + "/documents/" + document.ID.String(), status: http.StatusNotFound},
+		{name: "invalid slug", path: "/api/posts/notes/documents/" + document.ID.String(), status: http.StatusBadRequest},
+		{name: "invalid id", path: "/api/posts/" + document.PostSlug.String() + "/documents/1", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("GET %s = %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			got := models.Document{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != document.ID || got.Filename != document.Filename {
+				t.Errorf("GET %s = %+v", tt.path, got)
+			}
+		})
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userId := uuid.New()
+	slug := uuid.New()
+	notes := models.Document{ID: uuid.New(), PostSlug: slug, Filename: "notes.md", ContentType: "text/markdown"}
+	week1 := models.Document{ID: uuid.New(), PostSlug: slug, Filename: "week1.md", ContentType: "text/markdown"}
+	post := models.Post{Slug: slug, AuthorID: userId, Documents: []*models.Document{&notes, &week1}}
+
+	revision := func(filename string, content string) models.DocumentRevision {
+		return models.DocumentRevision{
+			ID: uuid.New(), DocumentID: notes.ID, PostSlug: slug, Filename: filename, ContentType: "text/markdown",
+			ContentHash: models.ContentHash([]byte(content)), Content: []byte(content),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		userId   uuid.UUID
+		revision models.DocumentRevision
+		status   int
+	}{
+		{name: "content", userId: userId, revision: revision("notes.md", "# Notes"), status: http.StatusOK},
+		{name: "previous filename", userId: userId, revision: revision("old.md", "# Notes"), status: http.StatusOK},
+		{name: "empty content", userId: userId, revision: revision("notes.md", ""), status: http.StatusOK},
+		{name: "filename taken since", userId: userId, revision: revision("week1.md", "# Week 1"), status: http.StatusConflict},
+		{name: "student", userId: uuid.New(), revision: revision("notes.md", "# Notes"), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents := &fakeDocuments{documents: []models.Document{notes, week1}, revisions: []models.DocumentRevision{tt.revision}}
+			documentChan := make(chan models.DocumentChanItem, 1)
+			controller := NewDocumentsController(config.Config{}, documents, fakePosts{post: post}, fakeSearchCache{}, nil, nil, documentChan)
+
+			router := gin.New()
+			router.POST("/api/posts/:slug/documents/:id/revisions/:revisionId/restore", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, tt.userId) }, controller.RestoreRevision)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/posts/"+slug.String()+"/documents/"+notes.ID.String()+"/revisions/"+tt.revision.ID.String()+"/restore", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("RestoreRevision() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				if len(documents.updates) != 0 || len(documentChan) != 0 {
+					t.Errorf("RestoreRevision() updated %+v, want nothing", documents.updates)
+				}
+				return
+			}
+
+			if len(documents.updates) != 1 || len(documentChan) != 1 {
+				t.Fatalf("RestoreRevision() updated %d documents, queued %d, want 1", len(documents.updates), len(documentChan))
+			}
+
+			// The content hash tells the repository to restore the content,
+			// even when it is empty
+			update := documents.updates[0]
+			if update.Filename != tt.revision.Filename || string(update.Content) != string(tt.revision.Content) || update.ContentHash != tt.revision.ContentHash {
+				t.Errorf("RestoreRevision() update = %+v, want the revision %+v", update, tt.revision)
+			}
+		})
+	}
+}
+
+func TestUpdateDocumentUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userId := uuid.New()
+	slug := uuid.New()
+	notes := models.Document{ID: uuid.New(), PostSlug: slug, Filename: "notes.txt", ContentType: "text/plain"}
+	week1 := models.Document{ID: uuid.New(), PostSlug: slug, Filename: "week1.md", ContentType: "text/markdown"}
+	post := models.Post{Slug: slug, AuthorID: userId, Documents: []*models.Document{&notes, &week1}}
+
+	tests := []struct {
+		name        string
+		file        [2]string
+		filename    string
+		status      int
+		wantName    string
+		contentType string
+	}{
+		{name: "same type", file: [2]string{"draft.txt", "Recursion."}, status: http.StatusOK, wantName: "notes.txt", contentType: "text/plain"},
+		{name: "another type", file: [2]string{"notes-v2.md", "# Notes\n\nRecursion."}, status: http.StatusOK, wantName: "notes-v2.md", contentType: "text/markdown"},
+		{name: "another type and a filename", file: [2]string{"notes-v2.md", "# Notes"}, filename: "recursion.md", status: http.StatusOK, wantName: "recursion.md", contentType: "text/markdown"},
+		{name: "filename of another type", file: [2]string{"notes-v2.md", "# Notes"}, filename: "notes.pdf", status: http.StatusBadRequest},
+		{name: "renamed after a taken filename", file: [2]string{"week1.md", "# Week 1"}, status: http.StatusConflict},
+		{name: "empty file", file: [2]string{"notes.txt", ""}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents := &fakeDocuments{documents: []models.Document{notes, week1}}
+			documentChan := make(chan models.DocumentChanItem, 1)
+			controller := NewDocumentsController(config.Config{}, documents, fakePosts{post: post}, fakeSearchCache{}, nil, nil, documentChan)
+
+			router := gin.New()
+			router.PUT("/api/posts/:slug/documents/:id", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, userId) }, controller.UpdateDocument)
+
+			body := bytes.Buffer{}
+			mw := multipart.NewWriter(&body)
+			part, err := mw.CreateFormFile("file", tt.file[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(tt.file[1]))
+			if tt.filename != "" {
+				mw.WriteField("filename", tt.filename)
+			}
+			mw.Close()
+
+			req := httptest.NewRequest(http.MethodPut, "/api/posts/"+slug.String()+"/documents/"+notes.ID.String(), &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("UpdateDocument() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				if len(documents.updates) != 0 {
+					t.Errorf("UpdateDocument() updated %+v, want nothing", documents.updates)
+				}
+				return
+			}
+
+			got := models.Document{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != notes.ID || got.Filename != tt.wantName || got.ContentType != tt.contentType || string(got.Content) != tt.file[1] {
+				t.Errorf("UpdateDocument() = %s (%s), want %s (%s)", got.Filename, got.ContentType, tt.wantName, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestGetDocumentRaw(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	content := []byte("# Notes\n\nRecursion is a function calling itself.")
+	document := models.Document{
+		ID: uuid.New(), PostSlug: uuid.New(), Filename: "week 1/notes é.md", ContentType: "text/markdown",
+		ContentHash: models.ContentHash(content), Content: content,
+	}
+	etag := `"` + document.ContentHash + `"`
+	controller := NewDocumentsController(config.Config{}, &fakeDocuments{documents: []models.Document{document}}, nil, nil, nil, nil, nil)
+
+	router := gin.New()
+	router.GET("/api/posts/:slug/documents/:id/raw", controller.GetDocumentRaw)
+	target := "/api/posts/" + document.PostSlug.String() + "/documents/" + document.ID.String() + "/raw"
+
+	tests := []struct {
+		name         string
+		target       string
+		headers      map[string]string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{name: "whole content", target: target, status: http.StatusOK, body: string(content)},
+		{name: "same version", target: target, headers: map[string]string{"If-None-Match": etag}, status: http.StatusNotModified},
+		{name: "one of the versions", target: target, headers: map[string]string{"If-None-Match": `"0123", ` + etag}, status: http.StatusNotModified},
+		{name: "any version", target: target, headers: map[string]string{"If-None-Match": "*"}, status: http.StatusNotModified},
+		{name: "another version", target: target, headers: map[string]string{"If-None-Match": `"0123"`}, status: http.StatusOK, body: string(content)},
+		{name: "range", target: target, headers: map[string]string{"Range": "bytes=0-6"}, status: http.StatusPartialContent, body: "# Notes", contentRange: fmt.Sprintf("bytes 0-6/%d", len(content))},
+		{name: "range of the same version", target: target, headers: map[string]string{"Range": "bytes=2-6", "If-Range": etag}, status: http.StatusPartialContent, body: "Notes", contentRange: fmt.Sprintf("bytes 2-6/%d", len(content))},
+		{name: "range of another version", target: target, headers: map[string]string{"Range": "bytes=2-6", "If-Range": `"0123"`}, status: http.StatusOK, body: string(content)},
+		{name: "range out of the content", target: target, headers: map[string]string{"Range": "bytes=1000-"}, status: http.StatusRequestedRangeNotSatisfiable},
+		{name: "unknown document", target: "/api/posts/" + document.PostSlug.String() + "/documents/" + uuid.NewString() + "/raw", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("GetDocumentRaw() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusNotFound {
+				return
+			}
+
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("GetDocumentRaw() ETag = %s, want %s", got, etag)
+			}
+			if tt.status == http.StatusNotModified {
+				if w.Body.Len() != 0 {
+					t.Errorf("GetDocumentRaw() = %q, want no content", w.Body)
+				}
+				return
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("GetDocumentRaw() = %q, want %q", w.Body, tt.body)
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.contentRange && tt.status != http.StatusRequestedRangeNotSatisfiable {
+				t.Errorf("GetDocumentRaw() Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if tt.status == http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != document.ContentType {
+				t.Errorf("GetDocumentRaw() Content-Type = %q, want %q", got, document.ContentType)
+			}
+			_, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+			if err != nil || params["filename"] != "notes é.md" {
+				t.Errorf("GetDocumentRaw() Content-Disposition = %q, want the filename %q", w.Header().Get("Content-Disposition"), "notes é.md")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"webapp-go/webapp/middlewares"
 	"webapp-go/webapp/models"
 	"webapp-go/webapp/repositories"
 	"webapp-go/webapp/services"
@@ -16,11 +17,12 @@ type EmbeddingsController interface {
 
 type embeddingsController struct {
 	documentsRepo     repositories.DocumentsRepository
+	postsRepo         repositories.PostsRepository
 	embeddingsService services.EmbeddingsService
 }
 
-func NewEmbeddingsController(documentsRepo repositories.DocumentsRepository, embeddingsService services.EmbeddingsService) EmbeddingsController {
-	return embeddingsController{documentsRepo, embeddingsService}
+func NewEmbeddingsController(documentsRepo repositories.DocumentsRepository, postsRepo repositories.PostsRepository, embeddingsService services.EmbeddingsService) EmbeddingsController {
+	return embeddingsController{documentsRepo, postsRepo, embeddingsService}
 }
 
 type SearchGetParams struct {
@@ -40,7 +42,24 @@ func (this embeddingsController) GetSearchResult(c *gin.Context) {
 		return
 	}
 
-	searchResult, err := this.embeddingsService.GetSearchResult(c, uuid.MustParse(params.Slug), query)
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	// The explain mode exposes the prompt and the retrieved passages, so only
+	// the author of the post is allowed to use it
+	if query.Explain {
+		post, err := this.postsRepo.GetPost(c, uuid.MustParse(params.Slug))
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		if !post.IsAuthor(userId) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+	}
+
+	searchResult, err := this.embeddingsService.GetSearchResult(c, userId, uuid.MustParse(params.Slug), query)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
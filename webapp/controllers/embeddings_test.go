@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+	"webapp-go/webapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type fakePosts struct {
+	repositories.PostsRepository
+	post models.Post
+}
+
+func (this fakePosts) GetPost(c context.Context, slug uuid.UUID) (models.Post, error) {
+	if slug != this.post.Slug {
+		return models.Post{}, errors.New("no rows in result set")
+	}
+	return this.post, nil
+}
+
+// fakeEmbeddingsService answers every query, with the explain details when
+// they are asked for. The uploaded files are near duplicates of the files
+// named in near, among the existing documents then the uploaded ones.
+type fakeEmbeddingsService struct {
+	services.EmbeddingsService
+	existing []*models.Document
+	near     map[string]string
+	err      error
+}
+
+func (fakeEmbeddingsService) GetSearchResult(c context.Context, userId uuid.UUID, slug uuid.UUID, query models.SearchQuery) (models.SearchResult, error) {
+	result := models.SearchResult{Response: "A function that calls itself."}
+	if query.Explain {
+		result.Explain = &models.SearchExplain{Prompt: "Question: " + query.Query}
+	}
+	return result, nil
+}
+
+func (this fakeEmbeddingsService) NearDuplicates(c context.Context, slug uuid.UUID, documents []models.Document) ([][]models.DocumentWarning, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+
+	candidates := []models.Document{}
+	for _, d := range this.existing {
+		candidates = append(candidates, *d)
+	}
+	candidates = append(candidates, documents...)
+
+	warnings := make([][]models.DocumentWarning, len(documents))
+	for i, d := range documents {
+		warnings[i] = []models.DocumentWarning{}
+		for _, other := range candidates {
+			if other.Filename == this.near[d.Filename] {
+				warnings[i] = append(warnings[i], models.DocumentWarning{Kind: models.WARNING_NEAR_DUPLICATE, DocumentID: other.ID, Filename: other.Filename, Similarity: 0.97})
+			}
+		}
+	}
+	return warnings, nil
+}
+
+func TestGetSearchResultExplain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorId := uuid.New()
+	post := models.Post{Slug: uuid.New(), AuthorID: authorId}
+	controller := NewEmbeddingsController(nil, fakePosts{post: post}, fakeEmbeddingsService{})
+
+	tests := []struct {
+		name    string
+		userId  uuid.UUID
+		slug    uuid.UUID
+		query   string
+		status  int
+		explain bool
+	}{
+		{name: "author", userId: authorId, slug: post.Slug, query: "?query=recursion&explain=true", status: http.StatusOK, explain: true},
+		{name: "author without explain", userId: authorId, slug: post.Slug, query: "?query=recursion", status: http.StatusOK},
+		{name: "student", userId: uuid.New(), slug: post.Slug, query: "?query=recursion&explain=true", status: http.StatusUnauthorized},
+		{name: "student without explain", userId: uuid.New(), slug: post.Slug, query: "?query=recursion&explain=false", status: http.StatusOK},
+		{name: "unknown post", userId: authorId, slug: uuid.New(), query: "?query=recursion&explain=true", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/api/search/:slug", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, tt.userId) }, controller.GetSearchResult)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/"+tt.slug.String()+tt.query, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("GetSearchResult() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			got := models.SearchResult{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if (got.Explain != nil) != tt.explain {
+				t.Errorf("GetSearchResult() explain = %+v, want explain %v", got.Explain, tt.explain)
+			}
+		})
+	}
+}
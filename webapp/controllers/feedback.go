@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FeedbackController interface {
+	CreateFeedback(c *gin.Context)
+	GetFeedbackReport(c *gin.Context)
+}
+
+type feedbackController struct {
+	answersRepo repositories.AnswersRepository
+	postsRepo   repositories.PostsRepository
+}
+
+func NewFeedbackController(answersRepo repositories.AnswersRepository, postsRepo repositories.PostsRepository) FeedbackController {
+	return feedbackController{answersRepo, postsRepo}
+}
+
+type FeedbackCreateQuery struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (this feedbackController) CreateFeedback(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := FeedbackCreateQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// Only the user the answer was given to can rate it, the answers of the
+	// other users are not disclosed
+	answer, err := this.answersRepo.GetAnswer(c, uuid.MustParse(query.ID))
+	if err != nil || answer.UserID != userId {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	dto := models.AnswerFeedbackDTO{}
+	if err := c.ShouldBind(&dto); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	feedback, err := this.answersRepo.SaveFeedback(c, models.NewAnswerFeedback(userId, answer.ID, dto))
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, feedback)
+}
+
+type FeedbackReportQuery struct {
+	Slug string `uri:"slug" binding:"required,uuid"`
+}
+
+type FeedbackReportParams struct {
+	Limit int `form:"limit" binding:"min=1,max=100"`
+}
+
+func (this feedbackController) GetFeedbackReport(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := FeedbackReportQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	params := FeedbackReportParams{Limit: 20}
+	if err := c.ShouldBind(&params); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := this.postsRepo.GetPost(c, uuid.MustParse(query.Slug))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if post.AuthorID != userId {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	report, err := this.answersRepo.GetFeedbackReport(c, post.Slug, params.Limit)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type fakeAnswers struct {
+	repositories.AnswersRepository
+	answers  []models.SearchAnswer
+	feedback []models.AnswerFeedback
+}
+
+func (this *fakeAnswers) GetAnswer(c context.Context, id uuid.UUID) (models.SearchAnswer, error) {
+	for _, a := range this.answers {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return models.SearchAnswer{}, errors.New("no rows in result set")
+}
+
+func (this *fakeAnswers) SaveFeedback(c context.Context, feedback models.AnswerFeedback) (models.AnswerFeedback, error) {
+	this.feedback = append(this.feedback, feedback)
+	return feedback, nil
+}
+
+func (this *fakeAnswers) GetFeedbackReport(c context.Context, slug uuid.UUID, limit int) ([]models.AnswerFeedbackReport, error) {
+	report := []models.AnswerFeedbackReport{}
+	for _, a := range this.answers {
+		if a.PostSlug == slug && len(report) < limit {
+			report = append(report, models.AnswerFeedbackReport{AnswerID: a.ID, Query: a.Query})
+		}
+	}
+	return report, nil
+}
+
+func TestCreateFeedback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	studentId := uuid.New()
+	answer := models.SearchAnswer{ID: uuid.New(), PostSlug: uuid.New(), UserID: studentId, Query: "What is recursion?"}
+
+	tests := []struct {
+		name   string
+		userId uuid.UUID
+		id     string
+		body   string
+		status int
+	}{
+		{name: "upvote", userId: studentId, id: answer.ID.String(), body: `{"rating": 1, "comment": "Clear"}`, status: http.StatusCreated},
+		{name: "downvote", userId: studentId, id: answer.ID.String(), body: `{"rating": -1}`, status: http.StatusCreated},
+		{name: "answer of another user", userId: uuid.New(), id: answer.ID.String(), body: `{"rating": 1}`, status: http.StatusNotFound},
+		{name: "unknown answer", userId: studentId, id: uuid.NewString(), body: `{"rating": 1}`, status: http.StatusNotFound},
+		{name: "invalid id", userId: studentId, id: "1", body: `{"rating": 1}`, status: http.StatusBadRequest},
+		{name: "invalid rating", userId: studentId, id: answer.ID.String(), body: `{"rating": 5}`, status: http.StatusBadRequest},
+		{name: "missing rating", userId: studentId, id: answer.ID.String(), body: `{"comment": "Clear"}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answers := &fakeAnswers{answers: []models.SearchAnswer{answer}}
+			controller := NewFeedbackController(answers, nil)
+
+			router := gin.New()
+			router.POST("/api/answers/:id/feedback", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, tt.userId) }, controller.CreateFeedback)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/answers/"+tt.id+"/feedback", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("CreateFeedback() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusCreated {
+				if len(answers.feedback) != 0 {
+					t.Errorf("CreateFeedback() saved %+v, want nothing", answers.feedback)
+				}
+				return
+			}
+
+			dto := models.AnswerFeedbackDTO{}
+			json.Unmarshal([]byte(tt.body), &dto)
+			if len(answers.feedback) != 1 {
+				t.Fatalf("CreateFeedback() saved %d feedbacks, want 1", len(answers.feedback))
+			}
+			if got := answers.feedback[0]; got.AnswerID != answer.ID || got.UserID != studentId || got.Rating != dto.Rating || got.Comment != dto.Comment {
+				t.Errorf("CreateFeedback() saved %+v, want %+v", got, dto)
+			}
+		})
+	}
+}
+
+func TestGetFeedbackReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorId := uuid.New()
+	post := models.Post{Slug: uuid.New(), AuthorID: authorId}
+	answers := &fakeAnswers{answers: []models.SearchAnswer{
+		{ID: uuid.New(), PostSlug: post.Slug, Query: "What is recursion?"},
+		{ID: uuid.New(), PostSlug: post.Slug, Query: "What is a tree?"},
+		{ID: uuid.New(), PostSlug: uuid.New(), Query: "What is a graph?"},
+	}}
+	controller := NewFeedbackController(answers, fakePosts{post: post})
+
+	tests := []struct {
+		name    string
+		userId  uuid.UUID
+		slug    string
+		query   string
+		status  int
+		answers int
+	}{
+		{name: "author", userId: authorId, slug: post.Slug.String(), status: http.StatusOK, answers: 2},
+		{name: "limit", userId: authorId, slug: post.Slug.String(), query: "?limit=1", status: http.StatusOK, answers: 1},
+		{name: "limit too large", userId: authorId, slug: post.Slug.String(), query: "?limit=500", status: http.StatusBadRequest},
+		{name: "student", userId: uuid.New(), slug: post.Slug.String(), status: http.StatusUnauthorized},
+		{name: "unknown post", userId: authorId, slug: uuid.NewString(), status: http.StatusNotFound},
+		{name: "invalid slug", userId: authorId, slug: "notes", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/api/posts/:slug/feedback", func(c *gin.Context) { c.Set(middlewares.USER_ID_KEY, tt.userId) }, controller.GetFeedbackReport)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts/"+tt.slug+"/feedback"+tt.query, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("GetFeedbackReport() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			report := []models.AnswerFeedbackReport{}
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report) != tt.answers {
+				t.Errorf("GetFeedbackReport() = %d answers, want %d", len(report), tt.answers)
+			}
+		})
+	}
+}
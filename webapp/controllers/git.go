@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+	"webapp-go/webapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GitController interface {
+	GetSource(c *gin.Context)
+	SaveSource(c *gin.Context)
+	DeleteSource(c *gin.Context)
+	SyncSource(c *gin.Context)
+	Webhook(c *gin.Context)
+	GetDeliveries(c *gin.Context)
+}
+
+type gitController struct {
+	gitSourcesRepo repositories.GitSourcesRepository
+	postsRepo      repositories.PostsRepository
+	gitService     services.GitService
+	pushChan       chan<- models.GitPushChanItem
+}
+
+func NewGitController(gitSourcesRepo repositories.GitSourcesRepository, postsRepo repositories.PostsRepository, gitService services.GitService, pushChan chan<- models.GitPushChanItem) GitController {
+	return gitController{gitSourcesRepo, postsRepo, gitService, pushChan}
+}
+
+type GitSourceQuery struct {
+	Slug string `uri:"slug" binding:"required,uuid"`
+}
+
+// authorizedPost returns the post of the request when the user is its author.
+func (this gitController) authorizedPost(c *gin.Context) (models.Post, bool) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := GitSourceQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return models.Post{}, false
+	}
+
+	post, err := this.postsRepo.GetPost(c, uuid.MustParse(query.Slug))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return models.Post{}, false
+	}
+
+	if post.AuthorID != userId {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return models.Post{}, false
+	}
+
+	return post, true
+}
+
+func (this gitController) GetSource(c *gin.Context) {
+	post, ok := this.authorizedPost(c)
+	if !ok {
+		return
+	}
+
+	source, err := this.gitSourcesRepo.GetSource(c, post.Slug)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+func (this gitController) SaveSource(c *gin.Context) {
+	post, ok := this.authorizedPost(c)
+	if !ok {
+		return
+	}
+
+	dto := models.GitSourceDTO{}
+	if err := c.ShouldBind(&dto); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	source := models.NewGitSource(post.Slug, dto)
+	if err := this.gitService.CheckSource(c, source); err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := this.gitSourcesRepo.SaveSource(c, source)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+func (this gitController) DeleteSource(c *gin.Context) {
+	post, ok := this.authorizedPost(c)
+	if !ok {
+		return
+	}
+
+	_, err := this.gitSourcesRepo.DeleteSource(c, post.Slug)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (this gitController) SyncSource(c *gin.Context) {
+	post, ok := this.authorizedPost(c)
+	if !ok {
+		return
+	}
+
+	result, err := this.gitService.Sync(c, post.Slug)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Post is not linked to a repository"})
+		return
+	case errors.Is(err, services.ErrGitForbidden):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrGitFailed):
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// webhookMaxSize bounds the body of the push deliveries.
+const webhookMaxSize = 5 << 20
+
+// verifyWebhook checks the GitHub signature or the GitLab token of a delivery
+// against the secret of the source.
+func verifyWebhook(c *gin.Context, secret string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+
+	if signature := c.GetHeader("X-Hub-Signature-256"); signature != "" {
+		expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+
+	if token := c.GetHeader("X-Gitlab-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	return false
+}
+
+// webhookDelivery returns the event and the id of a delivery. The deliveries
+// without an id are identified by their body.
+func webhookDelivery(c *gin.Context, body []byte) (event string, deliveryId string) {
+	event = c.GetHeader("X-GitHub-Event")
+	deliveryId = c.GetHeader("X-GitHub-Delivery")
+	if event == "" {
+		event = c.GetHeader("X-Gitlab-Event")
+		deliveryId = c.GetHeader("X-Gitlab-Event-UUID")
+	}
+
+	if deliveryId == "" {
+		sum := sha256.Sum256(body)
+		deliveryId = hex.EncodeToString(sum[:])
+	}
+
+	return
+}
+
+// Webhook receives the push deliveries of the repository linked to the post.
+// The deliveries are signed with the webhook secret of the source, and a
+// delivery is only processed once. The sync is queued for the git worker, as
+// the repositories do not wait for long. When the queue is full the delivery
+// is refused before being recorded, so that it can be delivered again.
+func (this gitController) Webhook(c *gin.Context) {
+	query := GitSourceQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	slug := uuid.MustParse(query.Slug)
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, webhookMaxSize))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload is too large"})
+		return
+	}
+
+	source, err := this.gitSourcesRepo.GetSource(c, slug)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if !verifyWebhook(c, source.WebhookSecret, body) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	event, deliveryId := webhookDelivery(c, body)
+
+	isPush := event == "push" || event == "Push Hook"
+	if isPush && len(this.pushChan) == cap(this.pushChan) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Too many deliveries, retry later"})
+		return
+	}
+
+	delivery, inserted, err := this.gitSourcesRepo.CreateDelivery(c, models.NewWebhookDelivery(slug, deliveryId, event))
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if !inserted {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
+	}
+
+	push := models.GitPushPayload{}
+	if !isPush {
+		delivery.Status = models.DELIVERY_IGNORED
+		delivery.Message = "Not a push event"
+	} else if err := json.Unmarshal(body, &push); err != nil {
+		delivery.Status = models.DELIVERY_FAILED
+		delivery.Message = err.Error()
+	}
+
+	if delivery.Status != models.DELIVERY_RECEIVED {
+		if err := this.gitSourcesRepo.UpdateDelivery(c, delivery); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+		return
+	}
+
+	select {
+	case this.pushChan <- models.GitPushChanItem{Delivery: delivery, Push: push}:
+	default:
+		// Another delivery took the last place in the queue
+		delivery.Status = models.DELIVERY_FAILED
+		delivery.Message = "Too many deliveries"
+		if err := this.gitSourcesRepo.UpdateDelivery(c, delivery); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusServiceUnavailable, delivery)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func (this gitController) GetDeliveries(c *gin.Context) {
+	post, ok := this.authorizedPost(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := this.gitSourcesRepo.GetDeliveries(c, post.Slug, 50)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
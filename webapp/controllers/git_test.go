@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sign returns the GitHub signature of the body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := "0123456789abcdef"
+	body := []byte(`{"ref": "refs/heads/main"}`)
+
+	tests := []struct {
+		name    string
+		secret  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "github signature", secret: secret, headers: map[string]string{"X-Hub-Signature-256": sign(secret, body)}, want: true},
+		{name: "github signature of another secret", secret: secret, headers: map[string]string{"X-Hub-Signature-256": sign("fedcba9876543210", body)}},
+		{name: "github signature of another body", secret: secret, headers: map[string]string{"X-Hub-Signature-256": sign(secret, []byte("{}"))}},
+		{name: "github signature not in hex", secret: secret, headers: map[string]string{"X-Hub-Signature-256": "sha256=not-hex"}},
+		{name: "gitlab token", secret: secret, headers: map[string]string{"X-Gitlab-Token": secret}, want: true},
+		{name: "gitlab wrong token", secret: secret, headers: map[string]string{"X-Gitlab-Token": "fedcba9876543210"}},
+		{name: "missing secret", secret: "", headers: map[string]string{"X-Hub-Signature-256": sign("", body), "X-Gitlab-Token": ""}},
+		{name: "unsigned", secret: secret, headers: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			for k, v := range tt.headers {
+				c.Request.Header.Set(k, v)
+			}
+
+			if got := verifyWebhook(c, tt.secret, body); got != tt.want {
+				t.Errorf("verifyWebhook(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeGitSources struct {
+	repositories.GitSourcesRepository
+	source     models.GitSource
+	deliveries map[string]models.WebhookDelivery
+}
+
+func (this *fakeGitSources) GetSource(c context.Context, slug uuid.UUID) (models.GitSource, error) {
+	if slug != this.source.PostSlug {
+		return models.GitSource{}, errors.New("no rows in result set")
+	}
+	return this.source, nil
+}
+
+func (this *fakeGitSources) CreateDelivery(c context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, bool, error) {
+	if _, ok := this.deliveries[delivery.DeliveryID]; ok {
+		return delivery, false, nil
+	}
+	delivery.ID = uuid.New()
+	this.deliveries[delivery.DeliveryID] = delivery
+	return delivery, true, nil
+}
+
+func (this *fakeGitSources) UpdateDelivery(c context.Context, delivery models.WebhookDelivery) error {
+	this.deliveries[delivery.DeliveryID] = delivery
+	return nil
+}
+
+func TestWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := "0123456789abcdef"
+	slug := uuid.New()
+	push := []byte(`{"ref": "refs/heads/main", "after": "0123456789abcdef"}`)
+
+	sources := &fakeGitSources{source: models.GitSource{PostSlug: slug, WebhookSecret: secret}, deliveries: map[string]models.WebhookDelivery{}}
+	pushChan := make(chan models.GitPushChanItem, 1)
+	controller := NewGitController(sources, nil, nil, pushChan)
+
+	router := gin.New()
+	router.POST("/api/posts/:slug/git/webhook", controller.Webhook)
+
+	deliver := func(slug uuid.UUID, event string, id string, body []byte, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/"+slug.String()+"/git/webhook", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", id)
+		req.Header.Set("X-Hub-Signature-256", signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		slug      uuid.UUID
+		event     string
+		id        string
+		signature string
+		// drain lets the worker take the queued pushes first
+		drain  bool
+		status int
+		body   string
+		queued int
+	}{
+		{name: "push", slug: slug, event: "push", id: "1", signature: sign(secret, push), status: http.StatusAccepted, queued: 1},
+		{name: "repeated delivery", slug: slug, event: "push", id: "1", signature: sign(secret, push), drain: true, status: http.StatusOK, body: `{"status":"duplicate"}`},
+		{name: "another push", slug: slug, event: "push", id: "2", signature: sign(secret, push), status: http.StatusAccepted, queued: 1},
+		{name: "queue full", slug: slug, event: "push", id: "3", signature: sign(secret, push), status: http.StatusServiceUnavailable, queued: 1},
+		{name: "ping while the queue is full", slug: slug, event: "ping", id: "4", signature: sign(secret, push), status: http.StatusOK, queued: 1},
+		{name: "retry once the queue has room", slug: slug, event: "push", id: "3", signature: sign(secret, push), drain: true, status: http.StatusAccepted, queued: 1},
+		{name: "invalid signature", slug: slug, event: "push", id: "5", signature: sign("fedcba9876543210", push), drain: true, status: http.StatusUnauthorized},
+		{name: "unknown post", slug: uuid.New(), event: "push", id: "6", signature: sign(secret, push), status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.drain {
+				for len(pushChan) > 0 {
+					<-pushChan
+				}
+			}
+
+			w := deliver(tt.slug, tt.event, tt.id, push, tt.signature)
+			if w.Code != tt.status {
+				t.Fatalf("Webhook() = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Webhook() = %s, want %s", w.Body, tt.body)
+			}
+			if len(pushChan) != tt.queued {
+				t.Errorf("Webhook() queued %d pushes, want %d", len(pushChan), tt.queued)
+			}
+		})
+	}
+
+	if len(sources.deliveries) != 4 {
+		t.Errorf("Webhook() recorded %d deliveries, want 4", len(sources.deliveries))
+	}
+	if got := sources.deliveries["4"].Status; got != models.DELIVERY_IGNORED {
+		t.Errorf("Webhook() ping status = %q, want %q", got, models.DELIVERY_IGNORED)
+	}
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"webapp-go/webapp/middlewares"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type HistoryController interface {
+	GetHistory(c *gin.Context)
+	DeleteHistoryEntry(c *gin.Context)
+	ClearHistory(c *gin.Context)
+	UpdateHistorySettings(c *gin.Context)
+}
+
+type historyController struct {
+	historyService services.HistoryService
+}
+
+func NewHistoryController(historyService services.HistoryService) HistoryController {
+	return historyController{historyService}
+}
+
+func (this historyController) GetHistory(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := models.SearchHistoryQuery{Page: 1, Size: 20}
+	if err := c.ShouldBind(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := this.historyService.GetHistory(c, userId, query)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+type HistoryDeleteQuery struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (this historyController) DeleteHistoryEntry(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	query := HistoryDeleteQuery{}
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := this.historyService.DeleteEntry(c, userId, uuid.MustParse(query.ID)); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (this historyController) ClearHistory(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	if err := this.historyService.ClearHistory(c, userId); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (this historyController) UpdateHistorySettings(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
+	dto := models.SearchHistorySettingsDTO{}
+	if err := c.ShouldBind(&dto); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := this.historyService.SetEnabled(c, userId, dto.Enabled)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
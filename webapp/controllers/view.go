@@ -28,10 +28,11 @@ type viewController struct {
 	usersRepo         repositories.UsersRepository
 	documentsRepo     repositories.DocumentsRepository
 	embeddingsService services.EmbeddingsService
+	historyService    services.HistoryService
 }
 
-func NewViewController(postsRepo repositories.PostsRepository, usersRepo repositories.UsersRepository, documentsRepo repositories.DocumentsRepository, embeddingsService services.EmbeddingsService) ViewController {
-	return viewController{postsRepo, usersRepo, documentsRepo, embeddingsService}
+func NewViewController(postsRepo repositories.PostsRepository, usersRepo repositories.UsersRepository, documentsRepo repositories.DocumentsRepository, embeddingsService services.EmbeddingsService, historyService services.HistoryService) ViewController {
+	return viewController{postsRepo, usersRepo, documentsRepo, embeddingsService, historyService}
 }
 
 func (this viewController) GetIndexPage(c *gin.Context) {
@@ -71,7 +72,13 @@ func (this viewController) GetUserPage(c *gin.Context) {
 		return
 	}
 
-	c.HTML(http.StatusOK, "user.html", gin.H{"User": user})
+	history, err := this.historyService.GetHistory(c, userId, models.SearchHistoryQuery{Page: 1, Size: 20})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "user.html", gin.H{"User": user, "History": history})
 }
 
 type PostPageGetParams struct {
@@ -99,6 +106,14 @@ func (this viewController) GetPostPage(c *gin.Context) {
 		return
 	}
 
+	// The page shows the text of every document
+	for _, d := range post.Documents {
+		d.Content, err = this.documentsRepo.GetContent(c, *d)
+		if err != nil {
+			slog.Error("Error getting the content of the document with id", "id", d.ID, "error", err.Error())
+		}
+	}
+
 	c.HTML(http.StatusOK, "post.html", gin.H{
 		"Post": post, "User": user,
 	})
@@ -121,6 +136,8 @@ type SearchPageParams struct {
 }
 
 func (this viewController) SearchPost(c *gin.Context) {
+	userId := c.MustGet(middlewares.USER_ID_KEY).(uuid.UUID)
+
 	params := SearchPageParams{}
 	if err := c.ShouldBindUri(&params); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
@@ -133,7 +150,10 @@ func (this viewController) SearchPost(c *gin.Context) {
 		return
 	}
 
-	searchResult, err := this.embeddingsService.GetSearchResult(c, uuid.MustParse(params.Slug), query)
+	// The search page does not render the explain details
+	query.Explain = false
+
+	searchResult, err := this.embeddingsService.GetSearchResult(c, userId, uuid.MustParse(params.Slug), query)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -147,8 +167,8 @@ func (this viewController) SearchPost(c *gin.Context) {
 			continue
 		}
 
-		documents = append(documents, models.NewDocumentSearchResult(d.Filename, s.Score))
+		documents = append(documents, models.NewDocumentSearchResult(d.Filename, s))
 	}
 
-	c.HTML(http.StatusOK, "search", gin.H{"Documents": documents, "Response": searchResult.Response})
+	c.HTML(http.StatusOK, "search", gin.H{"ID": searchResult.ID, "Documents": documents, "Response": searchResult.Response})
 }
@@ -0,0 +1,224 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a line of a diff.
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line is a line of the old text, of the new text, or of both.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxEdits bounds the work done on very different texts, which are then
+// diffed as a whole replacement.
+const maxEdits = 4000
+
+// Lines returns the edits that turn the lines of a into the lines of b, with
+// the shortest edit script of Myers' algorithm.
+func Lines(a []string, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+func myers(a []string, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	// v[offset+k] is the furthest x reached on the diagonal k, and trace keeps
+	// the diagonals -d..d of v before each step d for the backtracking
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	trace := [][]int{}
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	lines := make([]Line, 0, n+m)
+	for _, text := range a {
+		lines = append(lines, Line{Delete, text})
+	}
+	for _, text := range b {
+		lines = append(lines, Line{Insert, text})
+	}
+
+	return lines
+}
+
+func backtrack(a []string, b []string, trace [][]int) []Line {
+	lines := []Line{}
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] holds the diagonals -d..d, so that k is at index k+d
+		v := trace[d]
+		at := func(k int) int {
+			if k < -d || k > d {
+				return 0
+			}
+			return v[k+d]
+		}
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, Line{Equal, a[x]})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				y--
+				lines = append(lines, Line{Insert, b[y]})
+			} else {
+				x--
+				lines = append(lines, Line{Delete, a[x]})
+			}
+		}
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+// SplitLines splits a text into lines without their line breaks.
+func SplitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Unified formats the differences between two texts as a unified diff with
+// the given number of lines of context around the changes. It is empty when
+// the texts have the same lines.
+func Unified(fromName string, toName string, from string, to string, context int) string {
+	lines := Lines(SplitLines(from), SplitLines(to))
+
+	changes := []int{}
+	for i, l := range lines {
+		if l.Op != Equal {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// Line numbers in a and b before each line of the diff
+	aLines := make([]int, len(lines)+1)
+	bLines := make([]int, len(lines)+1)
+	for i, l := range lines {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if l.Op != Insert {
+			aLines[i+1]++
+		}
+		if l.Op != Delete {
+			bLines[i+1]++
+		}
+	}
+
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-context, 0)
+		end := changes[i] + 1
+
+		// Changes closer than twice the context share a hunk
+		for i < len(changes) && changes[i]-context <= end+context {
+			end = changes[i] + 1
+			i++
+		}
+		end = min(end+context, len(lines))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aLines[start], aLines[end]-aLines[start]), hunkRange(bLines[start], bLines[end]-bLines[start]))
+		for _, l := range lines[start:end] {
+			out.WriteByte(byte(l.Op))
+			out.WriteString(l.Text)
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the lines of a hunk, where an empty range is numbered
+// after the line that precedes it.
+func hunkRange(before int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
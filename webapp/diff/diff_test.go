@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sides returns the old and the new lines of a diff.
+func sides(lines []Line) ([]string, []string) {
+	a, b := []string{}, []string{}
+	for _, l := range lines {
+		if l.Op != Insert {
+			a = append(a, l.Text)
+		}
+		if l.Op != Delete {
+			b = append(b, l.Text)
+		}
+	}
+
+	return a, b
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		edits int
+	}{
+		{name: "same", a: "abc", b: "abc", edits: 0},
+		{name: "empty", a: "", b: "", edits: 0},
+		{name: "from empty", a: "", b: "abc", edits: 3},
+		{name: "to empty", a: "abc", b: "", edits: 3},
+		{name: "insert in the middle", a: "abd", b: "abcd", edits: 1},
+		{name: "delete at the start", a: "xabc", b: "abc", edits: 1},
+		{name: "replace", a: "abc", b: "axc", edits: 2},
+		// The example of Myers' paper, whose shortest edit script has 5 edits
+		{name: "myers", a: "abcabba", b: "cbabac", edits: 5},
+		{name: "repeated lines", a: "aaaa", b: "aaba", edits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+			lines := Lines(a, b)
+
+			gotA, gotB := sides(lines)
+			if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+				t.Fatalf("Lines(%q, %q) = %v, does not turn one into the other", tt.a, tt.b, lines)
+			}
+
+			edits := 0
+			for _, l := range lines {
+				if l.Op != Equal {
+					edits++
+				}
+			}
+			if edits != tt.edits {
+				t.Errorf("Lines(%q, %q) has %d edits, want %d: %v", tt.a, tt.b, edits, tt.edits, lines)
+			}
+		})
+	}
+}
+
+func TestLinesMaxEdits(t *testing.T) {
+	a, b := []string{}, []string{}
+	for i := range maxEdits {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+
+	lines := Lines(a, b)
+	if len(lines) != 2*maxEdits {
+		t.Fatalf("Lines() of different texts has %d lines, want %d", len(lines), 2*maxEdits)
+	}
+
+	gotA, gotB := sides(lines)
+	if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+		t.Errorf("Lines() of different texts does not turn one into the other")
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: []string{}},
+		{text: "a", want: []string{"a"}},
+		{text: "a\n", want: []string{"a"}},
+		{text: "a\n\nb", want: []string{"a", "", "b"}},
+	}
+
+	for _, tt := range tests {
+		if got := SplitLines(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		context int
+		want    string
+	}{
+		{name: "same", from: "a\nb\n", to: "a\nb\n", context: 3, want: ""},
+		{
+			name:    "one change",
+			from:    "1\n2\n3\n4\n5\n",
+			to:      "1\n2\nthree\n4\n5\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n",
+		},
+		{
+			name:    "separate hunks",
+			from:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			to:      "one\n2\n3\n4\n5\n6\n7\neight\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+eight\n",
+		},
+		{
+			name:    "close changes share a hunk",
+			from:    "1\n2\n3\n4\n",
+			to:      "one\n2\n3\nfour\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n-4\n+four\n",
+		},
+		{
+			name:    "from empty",
+			from:    "",
+			to:      "a\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:    "to empty",
+			from:    "a\nb\n",
+			to:      "",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.from, tt.to, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
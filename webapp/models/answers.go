@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// SearchAnswer is a persisted search response, so that feedback can be given
+// on it later.
+type SearchAnswer struct {
+	bun.BaseModel `bun:"table:search_answers,alias:sa"`
+
+	ID        uuid.UUID       `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	PostSlug  uuid.UUID       `bun:"post_slug,type:uuid,notnull" json:"postSlug"`
+	UserID    uuid.UUID       `bun:"user_id,type:uuid,notnull" json:"userId"`
+	Query     string          `bun:"query,type:text,notnull" json:"query"`
+	Response  string          `bun:"response,type:text,notnull" json:"response"`
+	Scores    []DocumentScore `bun:"scores,type:jsonb,notnull" json:"scores"`
+	Model     string          `bun:"model,type:varchar(128),notnull" json:"model"`
+	CreatedAt time.Time       `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewSearchAnswer(userId uuid.UUID, slug uuid.UUID, query SearchQuery, result SearchResult, model string) SearchAnswer {
+	return SearchAnswer{PostSlug: slug, UserID: userId, Query: query.Query, Response: result.Response, Scores: result.Scores, Model: model}
+}
+
+type AnswerFeedbackDTO struct {
+	Rating  int    `json:"rating" form:"rating" binding:"required,oneof=-1 1"`
+	Comment string `json:"comment" form:"comment" binding:"max=2048"`
+}
+
+type AnswerFeedback struct {
+	bun.BaseModel `bun:"table:answer_feedback,alias:af"`
+
+	ID        uuid.UUID `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	AnswerID  uuid.UUID `bun:"answer_id,type:uuid,notnull,unique:answer_user" json:"answerId"`
+	UserID    uuid.UUID `bun:"user_id,type:uuid,notnull,unique:answer_user" json:"userId"`
+	Rating    int       `bun:"rating,notnull" json:"rating"`
+	Comment   string    `bun:"comment,type:text,notnull,default:''" json:"comment"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewAnswerFeedback(userId uuid.UUID, answerId uuid.UUID, f AnswerFeedbackDTO) AnswerFeedback {
+	return AnswerFeedback{AnswerID: answerId, UserID: userId, Rating: f.Rating, Comment: f.Comment}
+}
+
+// AnswerFeedbackReport aggregates the feedback given on one answer.
+type AnswerFeedbackReport struct {
+	AnswerID  uuid.UUID `bun:"answer_id" json:"answerId"`
+	Query     string    `bun:"query" json:"query"`
+	Response  string    `bun:"response" json:"response"`
+	Model     string    `bun:"model" json:"model"`
+	CreatedAt time.Time `bun:"created_at" json:"createdAt"`
+	Score     int       `bun:"score" json:"score"`
+	Up        int       `bun:"up" json:"up"`
+	Down      int       `bun:"down" json:"down"`
+	Comments  []string  `bun:"comments,array" json:"comments"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Blob is the content of a document kept by the Postgres blob store.
+type Blob struct {
+	bun.BaseModel `bun:"table:blobs,alias:b"`
+
+	Key       string    `bun:"key,pk,type:varchar(256)" json:"key"`
+	Content   []byte    `bun:"content,type:bytea,notnull,default:''" json:"-"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// NewContentKey returns a new key for a content of a post. Every content gets
+// its own key, so that deleting a document never removes a blob used by
+// another one.
+func NewContentKey(slug uuid.UUID) string {
+	return slug.String() + "/" + uuid.New().String()
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SearchCacheEntry struct {
+	bun.BaseModel `bun:"table:search_cache,alias:sc"`
+
+	ID              uuid.UUID       `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	PostSlug        uuid.UUID       `bun:"post_slug,type:uuid,notnull,unique:search_cache_key" json:"postSlug"`
+	Query           string          `bun:"query,type:text,notnull,unique:search_cache_key" json:"query"`
+	Limit           int             `bun:"search_limit,notnull,unique:search_cache_key" json:"limit"`
+	DocumentVersion int             `bun:"document_version,notnull,unique:search_cache_key" json:"documentVersion"`
+	Scores          []DocumentScore `bun:"scores,type:jsonb,notnull" json:"scores"`
+	Response        string          `bun:"response,type:text,notnull" json:"response"`
+	CreatedAt       time.Time       `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	ExpiresAt       time.Time       `bun:"expires_at,notnull" json:"expiresAt"`
+}
+
+func NewSearchCacheEntry(slug uuid.UUID, query SearchQuery, version int, result SearchResult, ttl time.Duration) SearchCacheEntry {
+	return SearchCacheEntry{
+		PostSlug:        slug,
+		Query:           NormaliseQuery(query.Query),
+		Limit:           query.Limit,
+		DocumentVersion: version,
+		Scores:          result.Scores,
+		Response:        result.Response,
+		ExpiresAt:       time.Now().Add(ttl),
+	}
+}
+
+func (this SearchCacheEntry) SearchResult() SearchResult {
+	return SearchResult{Scores: this.Scores, Response: this.Response}
+}
+
+// NormaliseQuery makes questions that differ only in case, whitespace or the
+// trailing punctuation share the same cache entry.
+func NormaliseQuery(query string) string {
+	query = strings.ToLower(query)
+	query = strings.Join(strings.Fields(query), " ")
+	query = strings.TrimRight(query, "?!. ")
+
+	return query
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNormaliseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "What is recursion?", want: "what is recursion"},
+		{query: "  what   is\trecursion \n", want: "what is recursion"},
+		{query: "WHAT IS RECURSION?!", want: "what is recursion"},
+		{query: "what is recursion ? ", want: "what is recursion"},
+		{query: "what is recursion...", want: "what is recursion"},
+		{query: "what is 3.5?", want: "what is 3.5"},
+		{query: "is f(x) recursive, or not?", want: "is f(x) recursive, or not"},
+		{query: "¿Qué es la RECURSIÓN?", want: "¿qué es la recursión"},
+		{query: "???", want: ""},
+		{query: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormaliseQuery(tt.query); got != tt.want {
+			t.Errorf("NormaliseQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewSearchCacheEntry(t *testing.T) {
+	slug := uuid.New()
+	result := SearchResult{Scores: []DocumentScore{{DocumentID: uuid.New(), Score: 0.9}}, Response: "Recursion is..."}
+
+	before := time.Now()
+	entry := NewSearchCacheEntry(slug, SearchQuery{Query: "  What is Recursion? ", Limit: 5}, 3, result, time.Hour)
+
+	if entry.PostSlug != slug || entry.Query != "what is recursion" || entry.Limit != 5 || entry.DocumentVersion != 3 {
+		t.Errorf("NewSearchCacheEntry() key = %v %q %d %d", entry.PostSlug, entry.Query, entry.Limit, entry.DocumentVersion)
+	}
+	if entry.ExpiresAt.Before(before.Add(time.Hour)) || entry.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("NewSearchCacheEntry() expires at %v, want an hour from now", entry.ExpiresAt)
+	}
+
+	cached := entry.SearchResult()
+	if cached.Response != result.Response || len(cached.Scores) != 1 || cached.Scores[0] != result.Scores[0] {
+		t.Errorf("SearchResult() = %+v, want %+v", cached, result)
+	}
+}
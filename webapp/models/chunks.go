@@ -0,0 +1,141 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+	"webapp-go/webapp/parsers"
+)
+
+// DocumentChunk is a part of a document that is embedded on its own.
+type DocumentChunk struct {
+	Index     int
+	Text      string
+	Page      int
+	Section   string
+	StartLine int
+	EndLine   int
+}
+
+// NewDocumentChunks splits the parsed content in chunks of at most size
+// characters. Paragraphs are kept together when possible and a chunk never
+// spans more than one section, so that every chunk keeps its page number and
+// marker.
+func NewDocumentChunks(content parsers.Content, size int) []DocumentChunk {
+	chunks := []DocumentChunk{}
+
+	for _, section := range content.Sections {
+		// Source code is split on lines, so that every chunk knows its range
+		if section.StartLine > 0 {
+			for _, lines := range splitCode(section.Text, section.StartLine, size) {
+				chunks = append(chunks, DocumentChunk{Index: len(chunks), Text: lines.text, Page: section.Page, Section: section.Section, StartLine: lines.start, EndLine: lines.end})
+			}
+			continue
+		}
+
+		// The breadcrumb of the headings gives every chunk its context
+		breadcrumb := strings.Join(section.Headings, " > ")
+		if breadcrumb == "" {
+			for _, text := range splitText(section.Text, size) {
+				chunks = append(chunks, DocumentChunk{Index: len(chunks), Text: text, Page: section.Page, Section: section.Section})
+			}
+			continue
+		}
+
+		textSize := size
+		if size > 0 {
+			textSize = max(size-len(breadcrumb)-2, size/2)
+		}
+		for _, text := range splitText(section.Text, textSize) {
+			chunks = append(chunks, DocumentChunk{Index: len(chunks), Text: breadcrumb + "\n\n" + text, Page: section.Page, Section: section.Section})
+		}
+	}
+
+	return chunks
+}
+
+type codeLines struct {
+	text  string
+	start int
+	end   int
+}
+
+// splitCode splits source code starting at the given line in chunks of at
+// most size characters, cutting only between lines. A single line longer
+// than a chunk is kept whole.
+func splitCode(text string, start int, size int) []codeLines {
+	lines := strings.Split(text, "\n")
+	if size <= 0 {
+		return []codeLines{{text, start, start + len(lines) - 1}}
+	}
+
+	parts := []codeLines{}
+	first, length := 0, 0
+	for i, line := range lines {
+		if i > first && length+len(line)+1 > size {
+			parts = append(parts, codeLines{strings.Join(lines[first:i], "\n"), start + first, start + i - 1})
+			first, length = i, 0
+		}
+		length += len(line) + 1
+	}
+	parts = append(parts, codeLines{strings.Join(lines[first:], "\n"), start + first, start + len(lines) - 1})
+
+	return parts
+}
+
+func splitText(text string, size int) []string {
+	if size <= 0 {
+		return []string{strings.TrimSpace(text)}
+	}
+
+	parts := []string{}
+	current := strings.Builder{}
+
+	flush := func() {
+		if t := strings.TrimSpace(current.String()); t != "" {
+			parts = append(parts, t)
+		}
+		current.Reset()
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > size {
+			flush()
+		}
+
+		// Paragraphs longer than a chunk are split on their own
+		for len(paragraph) > size {
+			cut := strings.LastIndexAny(paragraph[:size], " \n")
+			if cut <= 0 {
+				cut = size
+				for cut > 0 && !utf8.RuneStart(paragraph[cut]) {
+					cut--
+				}
+			}
+
+			// A chunk smaller than the first rune still holds that rune
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(paragraph)
+			}
+
+			flush()
+			current.WriteString(paragraph[:cut])
+			flush()
+
+			paragraph = strings.TrimSpace(paragraph[cut:])
+		}
+
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+
+	flush()
+
+	return parts
+}
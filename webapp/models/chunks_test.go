@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"webapp-go/webapp/parsers"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		size int
+		want []string
+	}{
+		{name: "no size", text: " one\n\ntwo ", size: 0, want: []string{"one\n\ntwo"}},
+		{name: "paragraphs together", text: "one\n\ntwo", size: 20, want: []string{"one\n\ntwo"}},
+		{name: "paragraphs apart", text: "first one\n\nsecond one", size: 12, want: []string{"first one", "second one"}},
+		{name: "long paragraph on spaces", text: "alpha beta gamma", size: 11, want: []string{"alpha beta", "gamma"}},
+		{name: "long word on runes", text: "ééé", size: 3, want: []string{"é", "é", "é"}},
+		{name: "size below a rune", text: "日本語", size: 1, want: []string{"日", "本", "語"}},
+		{name: "size below a rune with ascii", text: "a€b", size: 2, want: []string{"a", "€", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.size)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("splitText(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+			}
+
+			for _, part := range got {
+				if !utf8.ValidString(part) {
+					t.Errorf("splitText(%q, %d) cut a rune: %q", tt.text, tt.size, part)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDocumentChunks(t *testing.T) {
+	content := parsers.Content{Sections: []parsers.Section{
+		{Text: "intro", Page: 1},
+		{Text: "body text", Headings: []string{"Guide", "Setup"}},
+		{Text: "a\nb\nc", StartLine: 10},
+	}}
+
+	chunks := NewDocumentChunks(content, 100)
+	if len(chunks) != 3 {
+		t.Fatalf("NewDocumentChunks() = %d chunks, want 3", len(chunks))
+	}
+
+	if chunks[0].Text != "intro" || chunks[0].Page != 1 {
+		t.Errorf("chunk 0 = %+v", chunks[0])
+	}
+	if chunks[1].Text != "Guide > Setup\n\nbody text" {
+		t.Errorf("chunk 1 text = %q", chunks[1].Text)
+	}
+	if chunks[2].StartLine != 10 || chunks[2].EndLine != 12 {
+		t.Errorf("chunk 2 lines = %d-%d, want 10-12", chunks[2].StartLine, chunks[2].EndLine)
+	}
+	for i, c := range chunks {
+		if c.Index != i {
+			t.Errorf("chunk %d has index %d", i, c.Index)
+		}
+	}
+}
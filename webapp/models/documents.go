@@ -2,8 +2,8 @@ package models
 
 import (
 	"fmt"
-	"log"
 	"time"
+	"webapp-go/webapp/parsers"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
@@ -16,35 +16,106 @@ type DocumentDTO struct {
 	PostSlug    uuid.UUID `json:"postSlug"`
 }
 
+type DocumentImportDTO struct {
+	Url      string `json:"url" form:"url" binding:"required,url"`
+	Filename string `json:"filename" form:"filename" binding:"max=128"`
+}
+
+// DocumentFilenameMaxLength is the length of the filename column.
+const DocumentFilenameMaxLength = 128
+
+// Indexing statuses of a document.
+const (
+	INDEX_PENDING = "pending"
+	INDEX_DONE    = "indexed"
+	INDEX_FAILED  = "failed"
+)
+
+// Document is the metadata of an uploaded file, its content is kept in the
+// blob store under the content key and is only loaded with GetContent. The
+// title, language and counts are extracted by the parser on upload, the
+// warnings about duplicates are updated once the document is indexed.
 type Document struct {
 	bun.BaseModel `bun:"table:documents,alias:d"`
 
-	ID          uuid.UUID `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
-	Filename    string    `bun:"filename,type:varchar(128),notnull,unique:post_group" json:"filename"`
-	ContentType string    `bun:"content_type,type:varchar(128),notnull,default:'text/plain'" json:"contentType"`
-	Content     []byte    `bun:"content,type:bytea,notnull,default:''" json:"content"`
-	CreatedAt   time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
-	PostSlug    uuid.UUID `bun:"post_slug,type:uuid,notnull,unique:post_group" json:"postSlug"`
+	ID          uuid.UUID         `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	Filename    string            `bun:"filename,type:varchar(128),notnull,unique:post_group" json:"filename"`
+	ContentType string            `bun:"content_type,type:varchar(128),notnull,default:'text/plain'" json:"contentType"`
+	ContentKey  string            `bun:"content_key,type:varchar(256),notnull,default:''" json:"-"`
+	ContentHash string            `bun:"content_hash,type:varchar(64),notnull,default:''" json:"contentHash"`
+	Size        int64             `bun:"size,notnull,default:0" json:"size"`
+	Content     []byte            `bun:"-" json:"content,omitempty"`
+	Title       string            `bun:"title,type:text,notnull,default:''" json:"title"`
+	Language    string            `bun:"language,type:varchar(16),notnull,default:''" json:"language"`
+	PageCount   int               `bun:"page_count,notnull,default:0" json:"pageCount"`
+	WordCount   int               `bun:"word_count,notnull,default:0" json:"wordCount"`
+	IndexStatus string            `bun:"index_status,type:varchar(16),nullzero,notnull,default:'pending'" json:"indexStatus"`
+	IndexError  string            `bun:"index_error,type:text,notnull,default:''" json:"indexError,omitempty"`
+	Metadata    map[string]string `bun:"metadata,type:jsonb,nullzero,notnull,default:'{}'" json:"metadata"`
+	Warnings    []DocumentWarning `bun:"warnings,type:jsonb,nullzero,notnull,default:'[]'" json:"warnings,omitempty"`
+	CreatedAt   time.Time         `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	PostSlug    uuid.UUID         `bun:"post_slug,type:uuid,notnull,unique:post_group" json:"postSlug"`
+}
+
+// Kinds of the warnings about an uploaded document.
+const (
+	WARNING_DUPLICATE      = "duplicate"
+	WARNING_NEAR_DUPLICATE = "nearDuplicate"
+)
+
+// DocumentWarning points to an existing document of the post with the same,
+// or nearly the same, content as an uploaded document.
+type DocumentWarning struct {
+	Kind       string    `bun:"-" json:"kind"`
+	DocumentID uuid.UUID `bun:"document_id" json:"documentId"`
+	Filename   string    `bun:"filename" json:"filename"`
+	Similarity float32   `bun:"similarity" json:"similarity"`
 }
 
 func NewDocument(d DocumentDTO) Document {
 	return Document{Filename: d.Filename, ContentType: d.ContentType, Content: d.Content, PostSlug: d.PostSlug}
 }
 
-func (this Document) ParseContent() string {
-	switch this.ContentType {
-	case "text/plain":
-		fallthrough
-	case "text/x-rst":
-		fallthrough
-	case "text/markdown":
-		return string(this.Content)
-	default:
-		log.Printf("Content type not supported: %s\n", this.ContentType)
-		return ""
+// Parse extracts the text and the structure of the document with the parser
+// registered for its content type or, failing that, for its extension.
+func (this Document) Parse() (parsers.Content, error) {
+	return parsers.Parse(this.Filename, this.ContentType, this.Content)
+}
+
+// Describe keeps what the parser extracted from the content of the document.
+func (this *Document) Describe(parsed parsers.Content) {
+	summary := parsers.Summarize(parsed)
+
+	this.Metadata = parsed.Metadata
+	if this.Metadata == nil {
+		this.Metadata = map[string]string{}
 	}
+	this.Title = summary.Title
+	this.Language = summary.Language
+	this.PageCount = summary.PageCount
+	this.WordCount = summary.WordCount
+}
+
+func (this Document) ParseContent() (string, error) {
+	parsed, err := this.Parse()
+	return parsed.Text(), err
 }
 
-func (this Document) FormatPrompt() string {
-	return fmt.Sprintf("Document Title: %s\nContent: %s\n", this.Filename, this.ParseContent())
+// Citation returns how a part of the document is referenced in the answers,
+// such as "slides.pdf p. 12" or "main.go:42-80, main".
+func Citation(filename string, page int, startLine int, endLine int, section string) string {
+	citation := filename
+	if page > 0 {
+		citation = fmt.Sprintf("%s p. %d", citation, page)
+	}
+	if startLine > 0 && endLine > startLine {
+		citation = fmt.Sprintf("%s:%d-%d", citation, startLine, endLine)
+	} else if startLine > 0 {
+		citation = fmt.Sprintf("%s:%d", citation, startLine)
+	}
+	if section != "" {
+		citation = fmt.Sprintf("%s, %s", citation, section)
+	}
+
+	return citation
 }
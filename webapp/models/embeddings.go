@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,22 +28,67 @@ func NewDocumentChanItem(c DocumentCommand, slug uuid.UUID, id uuid.UUID) Docume
 	return DocumentChanItem{Command: c, PostSlug: slug, ID: id}
 }
 
+// DocumentEmbedding is the embedding of one chunk of a document.
 type DocumentEmbedding struct {
 	bun.BaseModel `bun:"table:document_embeddings,alias:de"`
 
 	ID         uuid.UUID `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
 	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
-	DocumentID uuid.UUID `bun:"document_id,type:uuid,notnull,unique" json:"documentId"`
+	DocumentID uuid.UUID `bun:"document_id,type:uuid,notnull,unique:document_chunk" json:"documentId"`
+	ChunkIndex int       `bun:"chunk_index,notnull,default:0,unique:document_chunk" json:"chunkIndex"`
+	Content    string    `bun:"content,type:text,notnull,default:''" json:"content"`
+	Page       int       `bun:"page,notnull,default:0" json:"page"`
+	Section    string    `bun:"section,type:text,notnull,default:''" json:"section"`
+	StartLine  int       `bun:"start_line,notnull,default:0" json:"startLine"`
+	EndLine    int       `bun:"end_line,notnull,default:0" json:"endLine"`
 	Embeddings []float32 `bun:"embeddings,type:vector(4096),notnull" json:"embeddings"`
 
 	Document *Document `bun:"rel:has-one,join:document_id=id" json:"document"`
 }
 
-func NewDocumentEmbedding(id uuid.UUID, embeddings []float32) DocumentEmbedding {
-	return DocumentEmbedding{DocumentID: id, Embeddings: embeddings}
+func NewDocumentEmbedding(id uuid.UUID, chunk DocumentChunk, embeddings []float32) DocumentEmbedding {
+	return DocumentEmbedding{DocumentID: id, ChunkIndex: chunk.Index, Content: chunk.Text, Page: chunk.Page, Section: chunk.Section, StartLine: chunk.StartLine, EndLine: chunk.EndLine, Embeddings: embeddings}
 }
 
+// DocumentScore is a chunk of a document retrieved for a query.
 type DocumentScore struct {
-	DocumentID uuid.UUID `bun:"document_id,type:uuid,notnull,unique" json:"documentId"`
+	DocumentID uuid.UUID `bun:"document_id,type:uuid,notnull" json:"documentId"`
+	ChunkIndex int       `bun:"chunk_index" json:"chunkIndex"`
+	Content    string    `bun:"content" json:"content"`
+	Page       int       `bun:"page" json:"page,omitempty"`
+	Section    string    `bun:"section" json:"section,omitempty"`
+	StartLine  int       `bun:"start_line" json:"startLine,omitempty"`
+	EndLine    int       `bun:"end_line" json:"endLine,omitempty"`
 	Score      float32   `bun:"score" json:"score"`
 }
+
+func (this DocumentScore) Citation(filename string) string {
+	return Citation(filename, this.Page, this.StartLine, this.EndLine, this.Section)
+}
+
+func (this DocumentScore) FormatPrompt(filename string) string {
+	return fmt.Sprintf("Document Title: %s\nContent: %s\n", this.Citation(filename), this.Content)
+}
+
+// EmbeddingCacheEntry stores the embedding of a piece of content for a given
+// model, so that the same content is never embedded twice.
+type EmbeddingCacheEntry struct {
+	bun.BaseModel `bun:"table:embedding_cache,alias:ec"`
+
+	ID          uuid.UUID `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	ContentHash string    `bun:"content_hash,type:char(64),notnull,unique:embedding_cache_key" json:"contentHash"`
+	Model       string    `bun:"model,type:varchar(128),notnull,unique:embedding_cache_key" json:"model"`
+	Embeddings  []float32 `bun:"embeddings,type:vector(4096),notnull" json:"embeddings"`
+	CreatedAt   time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewEmbeddingCacheEntry(hash string, model string, embeddings []float32) EmbeddingCacheEntry {
+	return EmbeddingCacheEntry{ContentHash: hash, Model: model, Embeddings: embeddings}
+}
+
+// ContentHash returns the hex encoded sha256 of the content.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
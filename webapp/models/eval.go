@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type EvalDataset struct {
+	Questions []EvalQuestion `yaml:"questions" json:"questions"`
+}
+
+// EvalQuestion is a question with the filenames of the documents that should
+// be retrieved for it and an optional reference answer.
+type EvalQuestion struct {
+	Question  string   `yaml:"question" json:"question"`
+	Documents []string `yaml:"documents" json:"documents"`
+	Answer    string   `yaml:"answer" json:"answer,omitempty"`
+}
+
+type EvalQuestionResult struct {
+	Question       string   `json:"question"`
+	Expected       []string `json:"expected"`
+	Retrieved      []string `json:"retrieved"`
+	Recall         float64  `json:"recall"`
+	ReciprocalRank float64  `json:"reciprocalRank"`
+	NDCG           float64  `json:"ndcg"`
+	Answer         string   `json:"answer,omitempty"`
+	AnswerScore    *float64 `json:"answerScore,omitempty"`
+}
+
+type EvalReport struct {
+	PostSlug    uuid.UUID            `json:"postSlug"`
+	Model       string               `json:"model"`
+	K           int                  `json:"k"`
+	Recall      float64              `json:"recall"`
+	MRR         float64              `json:"mrr"`
+	NDCG        float64              `json:"ndcg"`
+	AnswerScore *float64             `json:"answerScore,omitempty"`
+	Questions   []EvalQuestionResult `json:"questions"`
+}
+
+func (this EvalReport) FormatMarkdown() string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "# Evaluation of post %s\n\n", this.PostSlug)
+	fmt.Fprintf(&b, "| Model | Questions | Recall@%d | MRR | nDCG@%d | Answer score |\n", this.K, this.K)
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %s | %d | %.3f | %.3f | %.3f | %s |\n\n", this.Model, len(this.Questions), this.Recall, this.MRR, this.NDCG, formatOptionalScore(this.AnswerScore))
+
+	fmt.Fprintf(&b, "| Question | Expected | Retrieved | Recall | RR | nDCG | Answer score |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|---|\n")
+	for _, q := range this.Questions {
+		fmt.Fprintf(&b, "| %s | %s | %s | %.3f | %.3f | %.3f | %s |\n",
+			escapeMarkdownCell(q.Question),
+			escapeMarkdownCell(strings.Join(q.Expected, ", ")),
+			escapeMarkdownCell(strings.Join(q.Retrieved, ", ")),
+			q.Recall, q.ReciprocalRank, q.NDCG, formatOptionalScore(q.AnswerScore))
+	}
+
+	return b.String()
+}
+
+func formatOptionalScore(score *float64) string {
+	if score == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.3f", *score)
+}
+
+func escapeMarkdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Metadata keys of the documents that are synced from a Git repository.
+const (
+	DOCUMENT_SOURCE_KEY = "source"
+	DOCUMENT_SOURCE_GIT = "git"
+	DOCUMENT_GIT_BLOB   = "gitBlob"
+)
+
+type GitSourceDTO struct {
+	Url      string `json:"url" form:"url" binding:"required"`
+	Branch   string `json:"branch" form:"branch"`
+	PathGlob string `json:"pathGlob" form:"pathGlob"`
+}
+
+// GitSource links a post to a Git repository, the documents of the post are
+// synced with the files of the branch that match the path glob. The webhook
+// secret signs the push deliveries of the repository.
+type GitSource struct {
+	bun.BaseModel `bun:"table:git_sources,alias:gs"`
+
+	ID            uuid.UUID  `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	PostSlug      uuid.UUID  `bun:"post_slug,type:uuid,notnull,unique" json:"postSlug"`
+	Url           string     `bun:"url,type:text,notnull" json:"url"`
+	Branch        string     `bun:"branch,type:varchar(256),notnull,default:'main'" json:"branch"`
+	PathGlob      string     `bun:"path_glob,type:varchar(256),notnull,default:''" json:"pathGlob"`
+	LastCommit    string     `bun:"last_commit,type:varchar(64),notnull,default:''" json:"lastCommit"`
+	LastSyncedAt  *time.Time `bun:"last_synced_at" json:"lastSyncedAt"`
+	WebhookSecret string     `bun:"webhook_secret,type:varchar(64),notnull,default:''" json:"webhookSecret"`
+	CreatedAt     time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewGitSource(slug uuid.UUID, d GitSourceDTO) GitSource {
+	branch := d.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	return GitSource{PostSlug: slug, Url: d.Url, Branch: branch, PathGlob: d.PathGlob, WebhookSecret: hex.EncodeToString(secret)}
+}
+
+// GitSyncResult lists the changes made to the documents of a post by a sync.
+type GitSyncResult struct {
+	Commit    string   `json:"commit"`
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Deleted   []string `json:"deleted"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   []string `json:"skipped"`
+}
+
+// GitPushPayload is the part of a GitHub or GitLab push event that is used to
+// know which files changed.
+type GitPushPayload struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+// Paths returns the files changed by the commits of the push.
+func (this GitPushPayload) Paths() []string {
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, commit := range this.Commits {
+		for _, list := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, p := range list {
+				if !seen[p] {
+					seen[p] = true
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+
+	return paths
+}
+
+// Statuses of a webhook delivery.
+const (
+	DELIVERY_RECEIVED  = "received"
+	DELIVERY_PROCESSED = "processed"
+	DELIVERY_IGNORED   = "ignored"
+	DELIVERY_FAILED    = "failed"
+)
+
+// WebhookDelivery logs a delivery of the push webhook of a post. A delivery
+// is processed once, even when it is delivered again.
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries,alias:wd"`
+
+	ID         uuid.UUID `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	PostSlug   uuid.UUID `bun:"post_slug,type:uuid,notnull,unique:webhook_delivery" json:"postSlug"`
+	DeliveryID string    `bun:"delivery_id,type:varchar(128),notnull,unique:webhook_delivery" json:"deliveryId"`
+	Event      string    `bun:"event,type:varchar(64),notnull,default:''" json:"event"`
+	Commit     string    `bun:"commit,type:varchar(64),notnull,default:''" json:"commit"`
+	Status     string    `bun:"status,type:varchar(16),notnull" json:"status"`
+	Message    string    `bun:"message,type:text,notnull,default:''" json:"message"`
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewWebhookDelivery(slug uuid.UUID, deliveryId string, event string) WebhookDelivery {
+	return WebhookDelivery{PostSlug: slug, DeliveryID: deliveryId, Event: event, Status: DELIVERY_RECEIVED}
+}
+
+// GitPushChanItem is a push delivery waiting to be synced.
+type GitPushChanItem struct {
+	Delivery WebhookDelivery
+	Push     GitPushPayload
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SearchHistoryEntry struct {
+	bun.BaseModel `bun:"table:search_history,alias:sh"`
+
+	ID        uuid.UUID  `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID  `bun:"user_id,type:uuid,notnull" json:"userId"`
+	PostSlug  uuid.UUID  `bun:"post_slug,type:uuid,notnull" json:"postSlug"`
+	Query     string     `bun:"query,type:text,notnull" json:"query"`
+	AnswerID  *uuid.UUID `bun:"answer_id,type:uuid" json:"answerId"`
+	CreatedAt time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+
+	Post *Post `bun:"rel:belongs-to,join:post_slug=slug" json:"post"`
+}
+
+func NewSearchHistoryEntry(userId uuid.UUID, slug uuid.UUID, query string, answerId uuid.UUID) SearchHistoryEntry {
+	entry := SearchHistoryEntry{UserID: userId, PostSlug: slug, Query: query}
+	if answerId != uuid.Nil {
+		entry.AnswerID = &answerId
+	}
+
+	return entry
+}
+
+type SearchHistoryQuery struct {
+	Page int `form:"page" binding:"min=1"`
+	Size int `form:"size" binding:"min=1,max=100"`
+}
+
+type SearchHistoryPage struct {
+	Items []SearchHistoryEntry `json:"items"`
+	Page  int                  `json:"page"`
+	Size  int                  `json:"size"`
+	Total int                  `json:"total"`
+}
+
+type SearchHistorySettingsDTO struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+}
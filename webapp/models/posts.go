@@ -21,6 +21,9 @@ type Post struct {
 	CreatedAt   time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
 	AuthorID    uuid.UUID `bun:"author_id,type:uuid,notnull" json:"authorId"`
 
+	// DocumentVersion is incremented every time a document of the post changes
+	DocumentVersion int `bun:"document_version,notnull,default:0" json:"documentVersion"`
+
 	Author    *User       `bun:"rel:belongs-to,join:author_id=id" json:"author"`
 	Documents []*Document `bun:"rel:has-many,join:slug=post_slug"`
 }
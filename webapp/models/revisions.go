@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// DocumentRevision is the content of a document after a change, which shares
+// its blob with the document until the next change. The author is empty for
+// the changes made by a sync.
+type DocumentRevision struct {
+	bun.BaseModel `bun:"table:document_revisions,alias:dr"`
+
+	ID          uuid.UUID  `bun:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+	DocumentID  uuid.UUID  `bun:"document_id,type:uuid,notnull" json:"documentId"`
+	PostSlug    uuid.UUID  `bun:"post_slug,type:uuid,notnull" json:"postSlug"`
+	Filename    string     `bun:"filename,type:varchar(128),notnull" json:"filename"`
+	ContentType string     `bun:"content_type,type:varchar(128),notnull,default:'text/plain'" json:"contentType"`
+	ContentKey  string     `bun:"content_key,type:varchar(256),notnull,default:''" json:"-"`
+	ContentHash string     `bun:"content_hash,type:varchar(64),notnull,default:''" json:"contentHash"`
+	Size        int64      `bun:"size,notnull,default:0" json:"size"`
+	Content     []byte     `bun:"-" json:"content,omitempty"`
+	AuthorID    *uuid.UUID `bun:"author_id,type:uuid" json:"authorId"`
+	CreatedAt   time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func NewDocumentRevision(document Document, authorId uuid.UUID) DocumentRevision {
+	revision := DocumentRevision{
+		DocumentID:  document.ID,
+		PostSlug:    document.PostSlug,
+		Filename:    document.Filename,
+		ContentType: document.ContentType,
+		ContentKey:  document.ContentKey,
+		ContentHash: document.ContentHash,
+		Size:        document.Size,
+		Content:     document.Content,
+	}
+	if authorId != uuid.Nil {
+		revision.AuthorID = &authorId
+	}
+
+	return revision
+}
+
+type DocumentDiffQuery struct {
+	From uuid.UUID `form:"from" binding:"required"`
+	To   uuid.UUID `form:"to"`
+}
+
+// DocumentDiff is the unified diff of the text of two revisions of a
+// document, the current content being used when there is no second revision.
+type DocumentDiff struct {
+	From DocumentRevision `json:"from"`
+	To   DocumentRevision `json:"to"`
+	Diff string           `json:"diff"`
+}
@@ -1,20 +1,98 @@
 package models
 
+import "github.com/google/uuid"
+
 type SearchQuery struct {
-	Query string `json:"query" form:"query"`
-	Limit int    `json:"limit" form:"limit"`
+	Query   string `json:"query" form:"query"`
+	Limit   int    `json:"limit" form:"limit"`
+	Explain bool   `json:"explain" form:"explain"`
 }
 
 type SearchResult struct {
+	ID       uuid.UUID       `json:"id"`
 	Scores   []DocumentScore `json:"scores"`
 	Response string          `json:"response"`
+	Explain  *SearchExplain  `json:"explain,omitempty"`
 }
 
 type DocumentSearchResult struct {
 	Filename string  `json:"filename"`
+	Citation string  `json:"citation"`
 	Score    float32 `json:"score"`
 }
 
-func NewDocumentSearchResult(filename string, score float32) DocumentSearchResult {
-	return DocumentSearchResult{Filename: filename, Score: score}
+func NewDocumentSearchResult(filename string, s DocumentScore) DocumentSearchResult {
+	return DocumentSearchResult{Filename: filename, Citation: s.Citation(filename), Score: s.Score}
+}
+
+// SearchExplain describes how a search response was produced, so that we can
+// tell apart retrieval problems from generation problems.
+type SearchExplain struct {
+	Prompt   string                 `json:"prompt"`
+	Model    string                 `json:"model"`
+	Passages []SearchExplainPassage `json:"passages"`
+	Tokens   SearchExplainTokens    `json:"tokens"`
+	Timings  SearchExplainTimings   `json:"timings"`
+}
+
+type SearchExplainPassage struct {
+	DocumentID      uuid.UUID `json:"documentId"`
+	Filename        string    `json:"filename"`
+	Citation        string    `json:"citation"`
+	ChunkIndex      int       `json:"chunkIndex"`
+	Content         string    `json:"content"`
+	Score           float32   `json:"score"`
+	NormalisedScore float32   `json:"normalisedScore"`
+}
+
+type SearchExplainTokens struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+	Total      int `json:"total"`
+}
+
+// SearchExplainTimings holds the duration of each search stage in milliseconds.
+type SearchExplainTimings struct {
+	Embedding  int64 `json:"embedding"`
+	Retrieval  int64 `json:"retrieval"`
+	Generation int64 `json:"generation"`
+	Total      int64 `json:"total"`
+}
+
+// NewSearchExplainPassages pairs the retrieved documents with their scores and
+// min-max normalises the scores to [0, 1] relative to the retrieved set.
+func NewSearchExplainPassages(scores []DocumentScore, documents map[uuid.UUID]Document) []SearchExplainPassage {
+	passages := []SearchExplainPassage{}
+	if len(scores) == 0 {
+		return passages
+	}
+
+	min, max := scores[0].Score, scores[0].Score
+	for _, s := range scores {
+		if s.Score < min {
+			min = s.Score
+		}
+		if s.Score > max {
+			max = s.Score
+		}
+	}
+
+	for _, s := range scores {
+		normalised := float32(1)
+		if max > min {
+			normalised = (s.Score - min) / (max - min)
+		}
+
+		passages = append(passages, SearchExplainPassage{
+			DocumentID:      s.DocumentID,
+			Filename:        documents[s.DocumentID].Filename,
+			Citation:        s.Citation(documents[s.DocumentID].Filename),
+			ChunkIndex:      s.ChunkIndex,
+			Content:         s.Content,
+			Score:           s.Score,
+			NormalisedScore: normalised,
+		})
+	}
+
+	return passages
 }
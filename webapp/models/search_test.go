@@ -0,0 +1,56 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewSearchExplainPassages(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	documents := map[uuid.UUID]Document{
+		a: {ID: a, Filename: "recursion.md"},
+		b: {ID: b, Filename: "trees.md"},
+		c: {ID: c, Filename: "graphs.md"},
+	}
+
+	tests := []struct {
+		name   string
+		scores []DocumentScore
+		want   []float32
+	}{
+		{name: "no scores", scores: nil, want: []float32{}},
+		{name: "one score", scores: []DocumentScore{{DocumentID: a, Score: 0.4}}, want: []float32{1}},
+		{name: "equal scores", scores: []DocumentScore{{DocumentID: a, Score: 0.7}, {DocumentID: b, Score: 0.7}}, want: []float32{1, 1}},
+		{
+			name:   "min-max",
+			scores: []DocumentScore{{DocumentID: a, Score: 0.9}, {DocumentID: b, Score: 0.6}, {DocumentID: c, Score: 0.5}},
+			want:   []float32{1, 0.25, 0},
+		},
+		{
+			name:   "negative similarity",
+			scores: []DocumentScore{{DocumentID: a, Score: 0.5}, {DocumentID: b, Score: -0.5}},
+			want:   []float32{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passages := NewSearchExplainPassages(tt.scores, documents)
+			if len(passages) != len(tt.want) {
+				t.Fatalf("NewSearchExplainPassages() = %d passages, want %d", len(passages), len(tt.want))
+			}
+
+			for i, p := range passages {
+				s := tt.scores[i]
+				if p.DocumentID != s.DocumentID || p.Filename != documents[s.DocumentID].Filename || p.Score != s.Score {
+					t.Errorf("NewSearchExplainPassages()[%d] = %+v, want the score %+v", i, p, s)
+				}
+				if math.Abs(float64(p.NormalisedScore-tt.want[i])) > 1e-6 {
+					t.Errorf("NewSearchExplainPassages()[%d] normalised = %v, want %v", i, p.NormalisedScore, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -15,14 +15,15 @@ type User struct {
 	Name           string    `bun:"name,type:varchar(128),notnull" json:"name"`
 	AvatarUrl      string    `bun:"avatar_url,type:varchar(256),notnull,default:''" json:"avatarUrl"`
 	CreatedAt      time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	SearchHistory  bool      `bun:"search_history,notnull,default:true" json:"searchHistory"`
 }
 
 func NewUser(u GitHubUser) User {
-	return User{GitHubUsername: &u.Login, Name: u.Name, AvatarUrl: u.AvatarUrl}
+	return User{GitHubUsername: &u.Login, Name: u.Name, AvatarUrl: u.AvatarUrl, SearchHistory: true}
 }
 
 func NewAnonymousUser() User {
-	return User{Name: "Anonymous", AvatarUrl: "https://avatars.githubusercontent.com/u/583231?v=4"}
+	return User{Name: "Anonymous", AvatarUrl: "https://avatars.githubusercontent.com/u/583231?v=4", SearchHistory: true}
 }
 
 func (this User) IsAnonymous() bool {
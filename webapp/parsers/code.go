@@ -0,0 +1,250 @@
+package parsers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// codeBoundary is the line, counted from 0, where the definition of a symbol
+// starts. An empty symbol marks top level code that is not a definition.
+type codeBoundary struct {
+	line   int
+	symbol string
+}
+
+// codeSections splits the lines of a source file on the boundaries, so that
+// every section holds one definition and knows its line range.
+func codeSections(lines []string, boundaries []codeBoundary) []Section {
+	if len(boundaries) == 0 || boundaries[0].line > 0 {
+		boundaries = append([]codeBoundary{{line: 0}}, boundaries...)
+	}
+
+	sections := []Section{}
+	for i, b := range boundaries {
+		end := len(lines)
+		if i+1 < len(boundaries) {
+			end = boundaries[i+1].line
+		}
+
+		start := b.line
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		if start == end {
+			continue
+		}
+
+		sections = append(sections, Section{
+			Text:      strings.Join(lines[start:end], "\n"),
+			Section:   b.symbol,
+			StartLine: start + 1,
+			EndLine:   end,
+		})
+	}
+
+	return sections
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+}
+
+// goReceiver returns the name of the type of a method receiver.
+func goReceiver(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiver(e.X)
+	case *ast.IndexExpr:
+		return goReceiver(e.X)
+	case *ast.IndexListExpr:
+		return goReceiver(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+
+	return ""
+}
+
+func goSymbol(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return goReceiver(d.Recv.List[0].Type) + "." + d.Name.Name
+		}
+		return d.Name.Name
+	case *ast.GenDecl:
+		names := []string{}
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return d.Tok.String()
+		}
+		return strings.Join(names, ", ")
+	}
+
+	return ""
+}
+
+// parseGo splits a Go file on its top level declarations, together with
+// their doc comments. Files with syntax errors keep the declarations that
+// could be parsed.
+func parseGo(content []byte) (parsed Content, err error) {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+
+	boundaries := []codeBoundary{}
+	if file != nil {
+		for _, decl := range file.Decls {
+			pos := decl.Pos()
+			if d, ok := decl.(*ast.FuncDecl); ok && d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+			if d, ok := decl.(*ast.GenDecl); ok && d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+
+			boundaries = append(boundaries, codeBoundary{line: fset.Position(pos).Line - 1, symbol: goSymbol(decl)})
+		}
+	}
+
+	parsed.Sections = codeSections(splitLines(content), boundaries)
+	return
+}
+
+var (
+	pythonDefinition = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+(\w+)`)
+	cDefinition      = regexp.MustCompile(`^(?:[A-Za-z_][\w\s\*]*[\s\*])?([A-Za-z_]\w*)\s*\([^;]*$`)
+	cType            = regexp.MustCompile(`^(?:typedef\s+)?(?:struct|union|enum)\s+(\w+)\s*\{?\s*$`)
+	cKeywords        = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "return": true, "sizeof": true}
+)
+
+// attachedStart moves the start of a definition up to the comments,
+// decorators or return types written right above it with the same
+// indentation, without going past the previous boundary.
+func attachedStart(lines []string, line int, indent string, previous int) int {
+	start := line
+	for start-1 > previous {
+		l := lines[start-1]
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || strings.HasPrefix(trimmed, "}") || len(l)-len(strings.TrimLeft(l, " \t")) != len(indent) {
+			break
+		}
+		start--
+	}
+
+	return start
+}
+
+// addBoundary adds the boundary of a symbol defined at the line. A definition
+// takes over the top level code right above it when it is attached to it.
+func addBoundary(boundaries []codeBoundary, lines []string, line int, indent string, symbol string) []codeBoundary {
+	previous := -1
+	if n := len(boundaries); n > 0 {
+		previous = boundaries[n-1].line
+		if symbol != "" && boundaries[n-1].symbol == "" {
+			if n > 1 {
+				previous = boundaries[n-2].line
+			} else {
+				previous = -1
+			}
+
+			start := attachedStart(lines, line, indent, previous)
+			if start <= boundaries[n-1].line {
+				boundaries = boundaries[:n-1]
+			}
+
+			return append(boundaries, codeBoundary{line: start, symbol: symbol})
+		}
+	}
+
+	return append(boundaries, codeBoundary{line: attachedStart(lines, line, indent, previous), symbol: symbol})
+}
+
+// isTopLevelCode reports whether the line is code written at the top level
+// of the file, which ends the definition above it.
+func isTopLevelCode(line string, comment string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+
+	return !strings.HasPrefix(line, comment) && !strings.ContainsAny(line[:1], "}{)]@#/*")
+}
+
+// parsePython splits a Python file on its top level functions and classes and
+// on the methods of the top level classes.
+func parsePython(content []byte) (parsed Content, err error) {
+	lines := splitLines(content)
+
+	boundaries := []codeBoundary{}
+	add := func(line int, indent string, symbol string) {
+		boundaries = addBoundary(boundaries, lines, line, indent, symbol)
+	}
+
+	class, classIndent := "", ""
+	for i, line := range lines {
+		m := pythonDefinition.FindStringSubmatch(line)
+		switch {
+		case m != nil && m[1] == "":
+			add(i, "", m[3])
+			class, classIndent = "", ""
+			if m[2] == "class" {
+				class = m[3]
+			}
+		case m != nil && class != "" && (classIndent == "" || classIndent == m[1]):
+			classIndent = m[1]
+			add(i, m[1], class+"."+m[3])
+		case isTopLevelCode(line, "#"):
+			class, classIndent = "", ""
+			if len(boundaries) > 0 && boundaries[len(boundaries)-1].symbol != "" {
+				add(i, "", "")
+			}
+		}
+	}
+
+	parsed.Sections = codeSections(lines, boundaries)
+	return
+}
+
+// parseC splits a C file on its function definitions and on the structs,
+// unions and enums declared at the top level.
+func parseC(content []byte) (parsed Content, err error) {
+	lines := splitLines(content)
+
+	boundaries := []codeBoundary{}
+	add := func(line int, symbol string) {
+		boundaries = addBoundary(boundaries, lines, line, "", symbol)
+	}
+
+	for i, line := range lines {
+		if m := cType.FindStringSubmatch(line); m != nil {
+			add(i, m[1])
+			continue
+		}
+
+		if m := cDefinition.FindStringSubmatch(line); m != nil && isTopLevelCode(line, "//") && !cKeywords[m[1]] {
+			add(i, m[1])
+			continue
+		}
+
+		if isTopLevelCode(line, "//") && len(boundaries) > 0 && boundaries[len(boundaries)-1].symbol != "" {
+			add(i, "")
+		}
+	}
+
+	parsed.Sections = codeSections(lines, boundaries)
+	return
+}
@@ -0,0 +1,142 @@
+package parsers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sectionRanges describes the sections as "symbol:start-end".
+func sectionRanges(sections []Section) []string {
+	ranges := []string{}
+	for _, s := range sections {
+		ranges = append(ranges, fmt.Sprintf("%s:%d-%d", s.Section, s.StartLine, s.EndLine))
+	}
+
+	return ranges
+}
+
+func TestParseCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   ParserFunc
+		content string
+		want    []string
+	}{
+		{
+			name:  "go",
+			parse: parseGo,
+			content: strings.Join([]string{
+				"package recursion", // 1
+				"",
+				"import \"fmt\"", // 3
+				"",
+				"// Factorial returns n!.", // 5
+				"func Factorial(n int) int {",
+				"\tif n == 0 {",
+				"\t\treturn 1",
+				"\t}",
+				"\treturn n * Factorial(n-1)",
+				"}", // 11
+				"",
+				"type Tree[T any] struct {", // 13
+				"\tLeft, Right *Tree[T]",
+				"}", // 15
+				"",
+				"func (t *Tree[T]) Depth() int { return 0 }", // 17
+				"",
+				"var (", // 19
+				"\ta, b = 1, 2",
+				")", // 21
+				"",
+				"func Print() { fmt.Println() }", // 23
+			}, "\n"),
+			want: []string{":1-1", "import:3-3", "Factorial:5-11", "Tree:13-15", "Tree.Depth:17-17", "a, b:19-21", "Print:23-23"},
+		},
+		{
+			name:  "go with a syntax error",
+			parse: parseGo,
+			content: strings.Join([]string{
+				"package recursion",
+				"",
+				"func Ok() {}",
+				"",
+				"func Broken( {",
+			}, "\n"),
+			want: []string{":1-1", "Ok:3-3", "Broken:5-5"},
+		},
+		{
+			name:  "python",
+			parse: parsePython,
+			content: strings.Join([]string{
+				"import functools", // 1
+				"",
+				"@functools.cache", // 3
+				"def factorial(n):",
+				"    return 1 if n == 0 else n * factorial(n - 1)", // 5
+				"",
+				"class Tree:", // 7
+				"    \"\"\"A binary tree.\"\"\"",
+				"",
+				"    # The depth of the tree", // 10
+				"    def depth(self):",
+				"        def helper(node):",
+				"            return 0",
+				"        return helper(self)", // 14
+				"",
+				"    async def walk(self):", // 16
+				"        pass",
+				"",
+				"print(factorial(5))", // 19
+			}, "\n"),
+			want: []string{":1-1", "factorial:3-5", "Tree:7-8", "Tree.depth:10-14", "Tree.walk:16-17", ":19-19"},
+		},
+		{
+			name:  "c",
+			parse: parseC,
+			content: strings.Join([]string{
+				"#include <stdio.h>", // 1
+				"",
+				"struct node {", // 3
+				"    struct node *left, *right;",
+				"};", // 5
+				"",
+				"/* n! */", // 7
+				"static int",
+				"factorial(int n)",
+				"{",
+				"    if (n == 0)",
+				"        return 1;",
+				"    return n * factorial(n - 1);",
+				"}", // 14
+				"",
+				"int main(void) {", // 16
+				"    printf(\"%d\\n\", factorial(5));",
+				"}", // 18
+			}, "\n"),
+			want: []string{":1-1", "node:3-5", "factorial:7-14", "main:16-18"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := tt.parse([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+
+			if got := sectionRanges(parsed.Sections); !slices.Equal(got, tt.want) {
+				t.Errorf("parse() sections = %q, want %q", got, tt.want)
+			}
+
+			// The sections hold the lines of their range
+			lines := splitLines([]byte(tt.content))
+			for _, s := range parsed.Sections {
+				if want := strings.Join(lines[s.StartLine-1:s.EndLine], "\n"); s.Text != want {
+					t.Errorf("parse() section %s = %q, want %q", s.Section, s.Text, want)
+				}
+			}
+		})
+	}
+}
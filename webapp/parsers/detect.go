@@ -0,0 +1,57 @@
+package parsers
+
+import (
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// genericContentTypes are detected for the formats that cannot be told apart
+// by their bytes alone, such as markdown, which is plain text, or the office
+// documents that are not recognised, which are zip archives.
+var genericContentTypes = map[string]bool{
+	"text/plain":               true,
+	"application/octet-stream": true,
+	"application/zip":          true,
+	"text/xml":                 true,
+	"application/json":         true,
+}
+
+// DetectContentType returns the MIME type of a document, detected from its
+// magic bytes and refined with the extension of the filename when the bytes
+// only give a generic type.
+func DetectContentType(filename string, content []byte) string {
+	contentType := normaliseContentType(mimetype.Detect(content).String())
+
+	t, ok := ExtensionContentType(filename)
+	if !ok {
+		return contentType
+	}
+
+	// A text format is told apart from another only by the extension, markdown
+	// with inline html is still markdown
+	if genericContentTypes[contentType] || (strings.HasPrefix(contentType, "text/") && strings.HasPrefix(t, "text/")) {
+		return t
+	}
+
+	return contentType
+}
+
+// MatchContentType reports whether the content type is in the list. The
+// entries of the list may also be wildcards such as "text/*".
+func MatchContentType(contentType string, list []string) bool {
+	contentType = normaliseContentType(contentType)
+
+	for _, entry := range list {
+		entry = normaliseContentType(entry)
+		if entry == contentType || entry == "*/*" {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(entry, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
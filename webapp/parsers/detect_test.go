@@ -0,0 +1,76 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+
+	// A zip without the office entries is only an archive
+	archive := bytes.Buffer{}
+	w := zip.NewWriter(&archive)
+	f, _ := w.Create("notes.txt")
+	f.Write([]byte("notes"))
+	w.Close()
+
+	tests := []struct {
+		filename string
+		content  []byte
+		want     string
+	}{
+		{filename: "notes.txt", content: []byte("Plain notes."), want: "text/plain"},
+		{filename: "notes.md", content: []byte("# Notes\n\nPlain notes."), want: "text/markdown"},
+		{filename: "notes.md", content: []byte("<div>Markdown with inline html</div>\n\n# Notes"), want: "text/markdown"},
+		{filename: "notes.rst", content: []byte("Notes\n=====\n"), want: "text/x-rst"},
+		{filename: "main.go", content: []byte("package main\n"), want: "text/x-go"},
+		{filename: "notebook.ipynb", content: []byte(`{"cells": [], "nbformat": 4}`), want: "application/x-ipynb+json"},
+		{filename: "slides.pdf", content: pdf, want: "application/pdf"},
+		// The bytes win over a misleading extension
+		{filename: "slides.md", content: pdf, want: "application/pdf"},
+		{filename: "slides.txt", content: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), want: "image/png"},
+		{filename: "notes.zip", content: archive.Bytes(), want: "application/zip"},
+		{filename: "notes.docx", content: archive.Bytes(), want: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{filename: "notes", content: []byte("Plain notes."), want: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := DetectContentType(tt.filename, tt.content); got != tt.want {
+				t.Errorf("DetectContentType(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	list := []string{"text/*", "application/pdf", " Application/X-Ipynb+JSON "}
+
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "text/markdown", want: true},
+		{contentType: "text/plain; charset=utf-8", want: true},
+		{contentType: "application/pdf", want: true},
+		{contentType: "application/x-ipynb+json", want: true},
+		{contentType: "application/zip", want: false},
+		{contentType: "textual/plain", want: false},
+		{contentType: "image/png", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchContentType(tt.contentType, list); got != tt.want {
+			t.Errorf("MatchContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+
+	if !MatchContentType("image/png", []string{"*/*"}) {
+		t.Errorf("MatchContentType() with */* = false")
+	}
+	if MatchContentType("text/plain", nil) {
+		t.Errorf("MatchContentType() with no list = true")
+	}
+}
@@ -0,0 +1,211 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkipped are the elements that never contain readable content, either
+// because they are code or because they are navigation chrome. Forms are kept,
+// as some frameworks wrap the whole page in one, and so are the headers of
+// articles, which hold their title and lede, while the site header is not.
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+	atom.Nav:      true,
+	atom.Footer:   true,
+}
+
+var htmlHeadings = map[atom.Atom]int{
+	atom.H1: 1,
+	atom.H2: 2,
+	atom.H3: 3,
+	atom.H4: 4,
+	atom.H5: 5,
+	atom.H6: 6,
+}
+
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+}
+
+type htmlWriter struct {
+	output  strings.Builder
+	current strings.Builder
+	space   bool
+	// item is set while the current line is a list item and broken after a
+	// line break, those lines are only separated by a newline from the
+	// previous one, while the blocks are separated by a blank line
+	item     bool
+	lastItem bool
+	broken   bool
+	lists    []atom.Atom
+	counts   []int
+	title    string
+	// articles is the depth of article and main elements being walked
+	articles int
+}
+
+// text appends the text collapsing the whitespace the way a browser would.
+func (this *htmlWriter) text(s string) {
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		this.space = this.space || s != ""
+		return
+	}
+
+	leading := strings.TrimLeftFunc(s, unicode.IsSpace) != s
+	line := this.current.String()
+	if (leading || this.space) && line != "" && !strings.HasSuffix(line, " ") {
+		this.current.WriteString(" ")
+	}
+
+	this.current.WriteString(collapsed)
+	this.space = strings.TrimRightFunc(s, unicode.IsSpace) != s
+}
+
+func (this *htmlWriter) flush() {
+	line := strings.TrimRightFunc(this.current.String(), unicode.IsSpace)
+	this.current.Reset()
+	this.space = false
+
+	item, broken := this.item, this.broken
+	this.item, this.broken = false, false
+
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if this.output.Len() > 0 {
+		if broken || (item && this.lastItem) {
+			this.output.WriteString("\n")
+		} else {
+			this.output.WriteString("\n\n")
+		}
+	}
+	this.output.WriteString(line)
+	this.lastItem = item
+}
+
+func (this *htmlWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		this.text(n.Data)
+		return
+	case html.ElementNode:
+		if htmlSkipped[n.DataAtom] || (n.DataAtom == atom.Header && this.articles == 0) {
+			return
+		}
+
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			this.articles++
+			defer func() { this.articles-- }()
+		}
+
+		if n.DataAtom == atom.Title {
+			if n.FirstChild != nil && this.title == "" {
+				this.title = strings.Join(strings.Fields(n.FirstChild.Data), " ")
+			}
+			return
+		}
+
+		if level, ok := htmlHeadings[n.DataAtom]; ok {
+			this.flush()
+			this.current.WriteString(strings.Repeat("#", level) + " ")
+			this.walkChildren(n)
+			this.flush()
+			return
+		}
+
+		switch n.DataAtom {
+		case atom.Br:
+			this.flush()
+			this.broken = true
+			return
+		case atom.Ul, atom.Ol:
+			this.flush()
+			this.lists = append(this.lists, n.DataAtom)
+			this.counts = append(this.counts, 0)
+			this.walkChildren(n)
+			this.lists = this.lists[:len(this.lists)-1]
+			this.counts = this.counts[:len(this.counts)-1]
+			this.flush()
+			return
+		case atom.Li:
+			this.flush()
+
+			marker := "-"
+			depth := len(this.lists)
+			if depth > 0 && this.lists[depth-1] == atom.Ol {
+				this.counts[depth-1]++
+				marker = fmt.Sprintf("%d.", this.counts[depth-1])
+			}
+			this.current.WriteString(strings.Repeat("  ", max(depth-1, 0)) + marker + " ")
+			this.item = true
+
+			this.walkChildren(n)
+			this.flush()
+			return
+		}
+
+		if htmlBlocks[n.DataAtom] {
+			this.flush()
+			this.walkChildren(n)
+			this.flush()
+			return
+		}
+	}
+
+	this.walkChildren(n)
+}
+
+func (this *htmlWriter) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		this.walk(c)
+	}
+}
+
+// parseHTML converts the html page to readable text, keeping the headings and
+// the lists as markdown, and dropping the scripts, styles and navigation.
+func parseHTML(content []byte) (parsed Content, err error) {
+	root, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return
+	}
+
+	w := htmlWriter{}
+	w.walk(root)
+	w.flush()
+
+	parsed.Sections = []Section{{Text: w.output.String()}}
+	if w.title != "" {
+		parsed.Metadata = map[string]string{"title": w.title}
+	}
+
+	return
+}
@@ -0,0 +1,65 @@
+package parsers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		fixture string
+		title   string
+		text    string
+	}{
+		{
+			fixture: "webforms.html",
+			title:   "Opening hours - City Library",
+			text:    "# Opening hours\n\nThe library is open from 9am to 6pm on weekdays.\n\n- Saturday: 10am to 4pm\n- Sunday: closed",
+		},
+		{
+			fixture: "article.html",
+			title:   "Tides explained | Ocean News",
+			text:    "# Why the sea rises twice a day\n\nThe Moon pulls on the oceans on both sides of the Earth.\n\nTides follow the Moon, with a smaller pull from the Sun.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := parseHTML(content)
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+
+			if got := parsed.Text(); got != tt.text {
+				t.Errorf("parseHTML() text = %q, want %q", got, tt.text)
+			}
+			if got := parsed.Metadata["title"]; got != tt.title {
+				t.Errorf("parseHTML() title = %q, want %q", got, tt.title)
+			}
+		})
+	}
+}
+
+func TestParseHTMLBlocks(t *testing.T) {
+	content := []byte(`<html><body>
+<h2>Steps</h2>
+<p>First paragraph.</p><p>Second<br>line.</p>
+<ol><li>Boil<ul><li>salted</li><li>water</li></ul></li><li>Add pasta</li></ol>
+<p>Enjoy.</p>
+</body></html>`)
+	want := "## Steps\n\nFirst paragraph.\n\nSecond\nline.\n\n1. Boil\n  - salted\n  - water\n2. Add pasta\n\nEnjoy."
+
+	parsed, err := parseHTML(content)
+	if err != nil {
+		t.Fatalf("parseHTML() error = %v", err)
+	}
+	if got := parsed.Text(); got != want {
+		t.Errorf("parseHTML() text = %q, want %q", got, want)
+	}
+}
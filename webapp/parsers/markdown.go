@@ -0,0 +1,204 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type heading struct {
+	level int
+	title string
+}
+
+// headingTree splits a document on its headings, every section knowing the
+// path of headings it is under.
+type headingTree struct {
+	stack    []heading
+	lines    []string
+	sections []Section
+}
+
+func (this *headingTree) heading(level int, title string) {
+	this.flush()
+
+	for len(this.stack) > 0 && this.stack[len(this.stack)-1].level >= level {
+		this.stack = this.stack[:len(this.stack)-1]
+	}
+	this.stack = append(this.stack, heading{level, title})
+}
+
+func (this *headingTree) line(line string) {
+	this.lines = append(this.lines, line)
+}
+
+func (this *headingTree) flush() {
+	text := strings.TrimSpace(strings.Join(this.lines, "\n"))
+	this.lines = nil
+
+	if text == "" {
+		return
+	}
+
+	headings := []string{}
+	for _, h := range this.stack {
+		headings = append(headings, h.title)
+	}
+
+	this.sections = append(this.sections, Section{Text: text, Section: strings.Join(headings, " > "), Headings: headings})
+}
+
+func (this *headingTree) content() (parsed Content) {
+	this.flush()
+
+	parsed.Sections = this.sections
+	for _, s := range this.sections {
+		if len(s.Headings) > 0 {
+			parsed.Metadata = map[string]string{"title": s.Headings[0]}
+			break
+		}
+	}
+
+	return
+}
+
+var (
+	markdownATX     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	markdownSetext  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	markdownFence   = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	markdownListing = regexp.MustCompile(`^ {0,3}([-*+>]|\d+[.)])(\s|$)`)
+)
+
+// parseMarkdown splits a markdown document on its ATX and setext headings.
+// Headings inside fenced code blocks and the front matter are ignored.
+func parseMarkdown(content []byte) (parsed Content, err error) {
+	lines := splitLines(content)
+	tree := headingTree{}
+
+	// The front matter is metadata, not content
+	title := ""
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if t := strings.TrimSpace(lines[i]); t == "---" || t == "..." {
+				lines = lines[i+1:]
+				break
+			}
+
+			if t, ok := strings.CutPrefix(lines[i], "title:"); ok {
+				title = strings.Trim(strings.TrimSpace(t), `"'`)
+			}
+		}
+	}
+
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := markdownFence.FindStringSubmatch(line); m != nil {
+			marker := m[1][:1]
+			if fence == "" {
+				fence = marker
+			} else if fence == marker {
+				fence = ""
+			}
+		}
+		if fence != "" {
+			tree.line(line)
+			continue
+		}
+
+		if m := markdownATX.FindStringSubmatch(line); m != nil && strings.TrimSpace(m[2]) != "" {
+			tree.heading(len(m[1]), strings.TrimSpace(m[2]))
+			continue
+		}
+
+		// A setext heading underlines a paragraph line
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+		if m := markdownSetext.FindStringSubmatch(next); m != nil && strings.TrimSpace(line) != "" && !markdownListing.MatchString(line) {
+			level := 2
+			if m[1][0] == '=' {
+				level = 1
+			}
+			tree.heading(level, strings.TrimSpace(line))
+			i++
+			continue
+		}
+
+		tree.line(line)
+	}
+
+	parsed = tree.content()
+	if title != "" {
+		parsed.Metadata = map[string]string{"title": title}
+	}
+
+	return
+}
+
+const rstAdornments = "=-`:'\"~^_*+#<>.!$%&(),/;?@[\\]{|}"
+
+// rstAdornment returns the character of a line made only of one repeated
+// punctuation character, such as "=====".
+func rstAdornment(line string) (rune, bool) {
+	line = strings.TrimRight(line, " \t")
+	if len(line) < 2 || !strings.ContainsRune(rstAdornments, rune(line[0])) {
+		return 0, false
+	}
+
+	if strings.Trim(line, line[:1]) != "" {
+		return 0, false
+	}
+
+	return rune(line[0]), true
+}
+
+// parseRST splits a reStructuredText document on its section titles. As in
+// reStructuredText, the level of a title is given by the order in which its
+// adornment style first appears.
+func parseRST(content []byte) (parsed Content, err error) {
+	lines := splitLines(content)
+	tree := headingTree{}
+	styles := map[string]int{}
+
+	level := func(style string) int {
+		if _, ok := styles[style]; !ok {
+			styles[style] = len(styles) + 1
+		}
+		return styles[style]
+	}
+
+	isTitle := func(line string) bool {
+		_, adornment := rstAdornment(line)
+		return strings.TrimSpace(line) != "" && line[0] != ' ' && line[0] != '\t' && !adornment
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// A title with both an overline and an underline
+		if c, ok := rstAdornment(line); ok && i+2 < len(lines) {
+			title := strings.TrimSpace(lines[i+1])
+			if u, ok := rstAdornment(lines[i+2]); ok && u == c && title != "" {
+				tree.heading(level("over"+string(c)), title)
+				i += 2
+				continue
+			}
+		}
+
+		// A title with an underline at least as long as the title
+		if i+1 < len(lines) && isTitle(line) {
+			if c, ok := rstAdornment(lines[i+1]); ok && utf8.RuneCountInString(strings.TrimSpace(lines[i+1])) >= utf8.RuneCountInString(strings.TrimSpace(line)) {
+				tree.heading(level(string(c)), strings.TrimSpace(line))
+				i++
+				continue
+			}
+		}
+
+		tree.line(line)
+	}
+
+	return tree.content(), nil
+}
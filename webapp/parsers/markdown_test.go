@@ -0,0 +1,159 @@
+package parsers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type headedSection struct {
+	section string
+	text    string
+}
+
+func headedSections(sections []Section) []headedSection {
+	got := []headedSection{}
+	for _, s := range sections {
+		got = append(got, headedSection{s.Section, s.Text})
+		if s.Section != strings.Join(s.Headings, " > ") {
+			got = append(got, headedSection{"headings " + strings.Join(s.Headings, "/"), ""})
+		}
+	}
+
+	return got
+}
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		title    string
+		sections []headedSection
+	}{
+		{
+			name: "headings",
+			content: strings.Join([]string{
+				"---",
+				`title: "Course notes"`,
+				"# Not a heading in the front matter",
+				"---",
+				"Intro line.",
+				"",
+				"# Recursion",
+				"",
+				"A function that calls itself.",
+				"",
+				"## Base case ##",
+				"",
+				"Stops here.",
+				"",
+				"```go",
+				"# not a heading",
+				"```",
+				"",
+				"Trees",
+				"-----",
+				"",
+				"- item",
+				"---",
+				"",
+				"#Not a heading either",
+				"# Iteration",
+				"Loops.",
+			}, "\n"),
+			title: "Course notes",
+			sections: []headedSection{
+				{"", "Intro line."},
+				{"Recursion", "A function that calls itself."},
+				{"Recursion > Base case", "Stops here.\n\n```go\n# not a heading\n```"},
+				{"Recursion > Trees", "- item\n---\n\n#Not a heading either"},
+				{"Iteration", "Loops."},
+			},
+		},
+		{
+			name:     "setext title",
+			content:  "Notes\n=====\n\nText.\r\n",
+			title:    "Notes",
+			sections: []headedSection{{"Notes", "Text."}},
+		},
+		{
+			name:     "no headings",
+			content:  "Just text.\n\nMore text.",
+			sections: []headedSection{{"", "Just text.\n\nMore text."}},
+		},
+		{
+			name:     "empty sections",
+			content:  "# One\n\n# Two\nText.",
+			title:    "Two",
+			sections: []headedSection{{"Two", "Text."}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseMarkdown([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("parseMarkdown() error = %v", err)
+			}
+
+			if got := headedSections(parsed.Sections); !slices.Equal(got, tt.sections) {
+				t.Errorf("parseMarkdown() sections = %q, want %q", got, tt.sections)
+			}
+			if got := parsed.Metadata["title"]; got != tt.title {
+				t.Errorf("parseMarkdown() title = %q, want %q", got, tt.title)
+			}
+		})
+	}
+}
+
+func TestParseRST(t *testing.T) {
+	content := strings.Join([]string{
+		"=======",
+		" Notes",
+		"=======",
+		"",
+		"Intro.",
+		"",
+		"Recursion",
+		"=========",
+		"",
+		"Calls itself.",
+		"",
+		"Base case",
+		"---------",
+		"",
+		"Stops.",
+		"",
+		"Iteration",
+		"=========",
+		"",
+		"Loops.",
+		"",
+		"Not a title",
+		"---",
+		"",
+		"Récursivité",
+		"-----------",
+		"",
+		"Encore.",
+	}, "\n")
+
+	parsed, err := parseRST([]byte(content))
+	if err != nil {
+		t.Fatalf("parseRST() error = %v", err)
+	}
+
+	want := []headedSection{
+		{"Notes", "Intro."},
+		{"Notes > Recursion", "Calls itself."},
+		{"Notes > Recursion > Base case", "Stops."},
+		{"Notes > Iteration", "Loops.\n\nNot a title\n---"},
+		{"Notes > Iteration > Récursivité", "Encore."},
+	}
+	if got := headedSections(parsed.Sections); !slices.Equal(got, want) {
+		t.Errorf("parseRST() sections = %q, want %q", got, want)
+	}
+	if got := parsed.Metadata["title"]; got != "Notes" {
+		t.Errorf("parseRST() title = %q, want %q", got, "Notes")
+	}
+}
@@ -0,0 +1,129 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// notebookText is a multiline string of a notebook, which is stored either as
+// a single string or as a list of lines.
+type notebookText string
+
+func (this *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*this = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*this = notebookText(text)
+
+	return nil
+}
+
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       notebookText               `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	EName      string                     `json:"ename"`
+	EValue     string                     `json:"evalue"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+}
+
+// NotebookParser extracts the markdown and code cells of a Jupyter notebook,
+// one section per cell. The text outputs of the code cells are only kept
+// when IncludeOutputs is set, images and other binary outputs never are.
+type NotebookParser struct {
+	IncludeOutputs bool
+}
+
+func (this NotebookParser) output(o notebookOutput) string {
+	switch o.OutputType {
+	case "stream":
+		return string(o.Text)
+	case "execute_result", "display_data":
+		// Only the plain text representation is kept, images are base64
+		text := notebookText("")
+		if data, ok := o.Data["text/plain"]; ok && json.Unmarshal(data, &text) == nil {
+			return string(text)
+		}
+	case "error":
+		return fmt.Sprintf("%s: %s", o.EName, o.EValue)
+	}
+
+	return ""
+}
+
+func (this NotebookParser) Parse(content []byte) (parsed Content, err error) {
+	nb := notebook{}
+	if err = json.Unmarshal(content, &nb); err != nil {
+		return
+	}
+
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.KernelSpec.Language
+	}
+
+	parsed.Metadata = map[string]string{}
+	if language != "" {
+		parsed.Metadata["kernelLanguage"] = language
+	}
+
+	for i, cell := range nb.Cells {
+		source := strings.TrimSpace(string(cell.Source))
+
+		text := ""
+		switch cell.CellType {
+		case "markdown", "raw":
+			text = source
+		case "code":
+			if source != "" {
+				text = fmt.Sprintf("```%s\n%s\n```", language, source)
+			}
+
+			if this.IncludeOutputs {
+				outputs := []string{}
+				for _, o := range cell.Outputs {
+					if t := strings.TrimSpace(this.output(o)); t != "" {
+						outputs = append(outputs, t)
+					}
+				}
+
+				if len(outputs) > 0 {
+					text = strings.TrimPrefix(fmt.Sprintf("%s\nOutput:\n%s", text, strings.Join(outputs, "\n")), "\n")
+				}
+			}
+		}
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		parsed.Sections = append(parsed.Sections, Section{Text: text, Section: fmt.Sprintf("Cell %d", i+1)})
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+package parsers
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseNotebook(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "recursion.ipynb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := "```python\ndef factorial(n):\n    return 1 if n == 0 else n * factorial(n - 1)\n\nfactorial(5)\n```"
+
+	tests := []struct {
+		name     string
+		parser   NotebookParser
+		texts    []string
+		sections []string
+	}{
+		{
+			name:     "without outputs",
+			parser:   NotebookParser{},
+			texts:    []string{"# Recursion\n\nA function that calls itself.", code, "See also: iteration."},
+			sections: []string{"Cell 1", "Cell 2", "Cell 5"},
+		},
+		{
+			name:     "with outputs",
+			parser:   NotebookParser{IncludeOutputs: true},
+			texts:    []string{"# Recursion\n\nA function that calls itself.", code + "\nOutput:\ncomputing\n120", "Output:\nRecursionError: maximum recursion depth exceeded", "See also: iteration."},
+			sections: []string{"Cell 1", "Cell 2", "Cell 3", "Cell 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := tt.parser.Parse(content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			texts := []string{}
+			for _, s := range parsed.Sections {
+				texts = append(texts, s.Text)
+			}
+			if !slices.Equal(texts, tt.texts) {
+				t.Errorf("Parse() texts = %q, want %q", texts, tt.texts)
+			}
+			if got := sectionNames(parsed.Sections); !slices.Equal(got, tt.sections) {
+				t.Errorf("Parse() sections = %q, want %q", got, tt.sections)
+			}
+			if parsed.Metadata["kernelLanguage"] != "python" {
+				t.Errorf("Parse() metadata = %v", parsed.Metadata)
+			}
+		})
+	}
+
+	if _, err := (NotebookParser{}).Parse([]byte(`{"cells": "not a list"}`)); err == nil {
+		t.Errorf("Parse() of an invalid notebook error = nil")
+	}
+}
@@ -0,0 +1,453 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// officeMaxEntrySize limits how much of a single archive entry is read, so
+// that a crafted document cannot exhaust the memory.
+const officeMaxEntrySize = 64 << 20
+
+var errOfficeEntryNotFound = errors.New("entry not found in archive")
+
+func readZipEntry(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return io.ReadAll(io.LimitReader(r, officeMaxEntrySize))
+	}
+
+	return nil, fmt.Errorf("%w: %s", errOfficeEntryNotFound, name)
+}
+
+// officeTitle returns the metadata with the title of the document properties,
+// which are in docProps/core.xml for Office and in meta.xml for OpenDocument,
+// or nil when there is no title.
+func officeTitle(archive *zip.Reader, name string) map[string]string {
+	data, err := readZipEntry(archive, name)
+	if err != nil {
+		return nil
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "title" || start.Name.Space != "http://purl.org/dc/elements/1.1/" {
+			continue
+		}
+
+		title := ""
+		if err := decoder.DecodeElement(&title, &start); err != nil || strings.TrimSpace(title) == "" {
+			return nil
+		}
+
+		return map[string]string{"title": strings.TrimSpace(title)}
+	}
+}
+
+// officeSections groups paragraphs in sections that start at every heading.
+type officeSections struct {
+	sections []Section
+	lines    []string
+	title    string
+}
+
+func (this *officeSections) heading(level int, text string) {
+	this.flush()
+
+	this.title = text
+	this.lines = append(this.lines, strings.Repeat("#", level)+" "+text)
+}
+
+func (this *officeSections) paragraph(text string) {
+	this.lines = append(this.lines, text)
+}
+
+func (this *officeSections) flush() {
+	text := strings.TrimSpace(strings.Join(this.lines, "\n"))
+	if text != "" {
+		this.sections = append(this.sections, Section{Text: text, Section: this.title})
+	}
+
+	this.lines = nil
+}
+
+var docxHeadingStyle = regexp.MustCompile(`(?i)^heading\s*(\d)$`)
+
+// parseDOCX extracts the paragraphs of a Word document, starting a new
+// section at every heading.
+func parseDOCX(content []byte) (parsed Content, err error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return
+	}
+
+	data, err := readZipEntry(archive, "word/document.xml")
+	if err != nil {
+		return
+	}
+
+	sections := officeSections{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	text := strings.Builder{}
+	level := 0
+	inText := false
+
+	for {
+		token, tokenErr := decoder.Token()
+		if tokenErr == io.EOF {
+			break
+		}
+		if tokenErr != nil {
+			return parsed, tokenErr
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				text.Reset()
+				level = 0
+			case "pStyle":
+				style := xmlAttr(t, "val")
+				if m := docxHeadingStyle.FindStringSubmatch(style); m != nil {
+					level, _ = strconv.Atoi(m[1])
+				} else if strings.EqualFold(style, "Title") {
+					level = 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				line := strings.TrimSpace(text.String())
+				if line == "" {
+					continue
+				}
+
+				if level > 0 {
+					sections.heading(level, line)
+				} else {
+					sections.paragraph(line)
+				}
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+
+	sections.flush()
+	parsed.Sections = sections.sections
+	parsed.Metadata = officeTitle(archive, "docProps/core.xml")
+
+	return
+}
+
+// parseODT extracts the paragraphs of an OpenDocument text, starting a new
+// section at every heading.
+func parseODT(content []byte) (parsed Content, err error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return
+	}
+
+	data, err := readZipEntry(archive, "content.xml")
+	if err != nil {
+		return
+	}
+
+	sections := officeSections{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	text := strings.Builder{}
+	level := 0
+	depth := 0
+
+	for {
+		token, tokenErr := decoder.Token()
+		if tokenErr == io.EOF {
+			break
+		}
+		if tokenErr != nil {
+			return parsed, tokenErr
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "h":
+				level, _ = strconv.Atoi(xmlAttr(t, "outline-level"))
+				level = max(level, 1)
+				fallthrough
+			case "p":
+				if depth == 0 {
+					text.Reset()
+				}
+				depth++
+			case "s":
+				count, _ := strconv.Atoi(xmlAttr(t, "c"))
+				text.WriteString(strings.Repeat(" ", max(count, 1)))
+			case "tab":
+				text.WriteString("\t")
+			case "line-break":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			if t.Name.Local != "p" && t.Name.Local != "h" {
+				continue
+			}
+
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			line := strings.TrimSpace(text.String())
+			if line != "" {
+				if t.Name.Local == "h" {
+					sections.heading(level, line)
+				} else {
+					sections.paragraph(line)
+				}
+			}
+			level = 0
+		case xml.CharData:
+			if depth > 0 {
+				text.Write(t)
+			}
+		}
+	}
+
+	sections.flush()
+	parsed.Sections = sections.sections
+	parsed.Metadata = officeTitle(archive, "meta.xml")
+
+	return
+}
+
+type officeRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func readRelationships(archive *zip.Reader, name string) (rels officeRelationships, err error) {
+	data, err := readZipEntry(archive, name)
+	if err != nil {
+		return
+	}
+
+	err = xml.Unmarshal(data, &rels)
+
+	return
+}
+
+var pptxSlideNumber = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// pptxSlides returns the paths of the slides in presentation order.
+func pptxSlides(archive *zip.Reader) []string {
+	slides := []string{}
+
+	rels, relsErr := readRelationships(archive, "ppt/_rels/presentation.xml.rels")
+	data, presentationErr := readZipEntry(archive, "ppt/presentation.xml")
+	if relsErr == nil && presentationErr == nil {
+		presentation := struct {
+			SlideIDs []struct {
+				Attrs []xml.Attr `xml:",any,attr"`
+			} `xml:"sldIdLst>sldId"`
+		}{}
+
+		if err := xml.Unmarshal(data, &presentation); err == nil {
+			targets := map[string]string{}
+			for _, r := range rels.Relationships {
+				targets[r.ID] = r.Target
+			}
+
+			for _, s := range presentation.SlideIDs {
+				for _, a := range s.Attrs {
+					if a.Name.Local == "id" && targets[a.Value] != "" {
+						slides = append(slides, path.Join("ppt", targets[a.Value]))
+					}
+				}
+			}
+		}
+	}
+
+	if len(slides) > 0 {
+		return slides
+	}
+
+	// Fall back to the numbering of the slide files
+	for _, f := range archive.File {
+		if pptxSlideNumber.MatchString(f.Name) {
+			slides = append(slides, f.Name)
+		}
+	}
+
+	sort.Slice(slides, func(i, j int) bool {
+		a, _ := strconv.Atoi(pptxSlideNumber.FindStringSubmatch(slides[i])[1])
+		b, _ := strconv.Atoi(pptxSlideNumber.FindStringSubmatch(slides[j])[1])
+		return a < b
+	})
+
+	return slides
+}
+
+// pptxText returns the paragraphs of a slide or of a notes page. The slide
+// number fields are skipped.
+func pptxText(data []byte) ([]string, error) {
+	paragraphs := []string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	text := strings.Builder{}
+	inText := false
+	inField := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return paragraphs, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				text.Reset()
+			case "t":
+				inText = true
+			case "fld":
+				inField = xmlAttr(t, "type") == "slidenum"
+			case "br":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "fld":
+				inField = false
+			case "p":
+				if line := strings.TrimSpace(text.String()); line != "" {
+					paragraphs = append(paragraphs, line)
+				}
+			}
+		case xml.CharData:
+			if inText && !inField {
+				text.Write(t)
+			}
+		}
+	}
+
+	return paragraphs, nil
+}
+
+// parsePPTX extracts the text and the speaker notes of every slide, with one
+// section per slide.
+func parsePPTX(content []byte) (parsed Content, err error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return
+	}
+
+	slides := pptxSlides(archive)
+
+	parsed.Metadata = officeTitle(archive, "docProps/core.xml")
+	if parsed.Metadata == nil {
+		parsed.Metadata = map[string]string{}
+	}
+	parsed.Metadata["pages"] = strconv.Itoa(len(slides))
+
+	for i, slide := range slides {
+		marker := fmt.Sprintf("Slide %d", i+1)
+
+		data, err := readZipEntry(archive, slide)
+		if err != nil {
+			return parsed, err
+		}
+
+		paragraphs, err := pptxText(data)
+		if err != nil {
+			return parsed, err
+		}
+
+		lines := append([]string{"# " + marker}, paragraphs...)
+
+		// The notes are linked from the relationships of the slide
+		rels, err := readRelationships(archive, path.Join(path.Dir(slide), "_rels", path.Base(slide)+".rels"))
+		if err == nil {
+			for _, r := range rels.Relationships {
+				if !strings.HasSuffix(r.Type, "/notesSlide") {
+					continue
+				}
+
+				notes, err := readZipEntry(archive, path.Join(path.Dir(slide), r.Target))
+				if err != nil {
+					continue
+				}
+
+				noteParagraphs, err := pptxText(notes)
+				if err == nil && len(noteParagraphs) > 0 {
+					lines = append(lines, "Notes:")
+					lines = append(lines, noteParagraphs...)
+				}
+			}
+		}
+
+		if len(lines) == 1 {
+			continue
+		}
+
+		parsed.Sections = append(parsed.Sections, Section{Text: strings.Join(lines, "\n"), Section: marker})
+	}
+
+	return
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
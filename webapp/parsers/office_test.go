@@ -0,0 +1,141 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// buildOffice builds a document archive with the entries, in order.
+func buildOffice(t *testing.T, entries ...string) []byte {
+	buf := bytes.Buffer{}
+	w := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(entries); i += 2 {
+		f, err := w.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(entries[i+1]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+const officeCore = `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title> Recursion </dc:title></cp:coreProperties>`
+
+func sectionNames(sections []Section) []string {
+	names := []string{}
+	for _, s := range sections {
+		names = append(names, s.Section)
+	}
+
+	return names
+}
+
+func TestParseDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Preface.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Recursion</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">A function </w:t></w:r><w:r><w:t>that calls itself.</w:t></w:r></w:p>
+<w:p></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading 2"/></w:pPr><w:r><w:t>Base case</w:t></w:r></w:p>
+<w:p><w:r><w:t>Stops</w:t><w:tab/><w:t>here.</w:t></w:r></w:p>
+</w:body></w:document>`
+
+	parsed, err := parseDOCX(buildOffice(t, "word/document.xml", document, "docProps/core.xml", officeCore))
+	if err != nil {
+		t.Fatalf("parseDOCX() error = %v", err)
+	}
+
+	if want := "Preface.\n\n# Recursion\nA function that calls itself.\n\n## Base case\nStops\there."; parsed.Text() != want {
+		t.Errorf("parseDOCX() text = %q, want %q", parsed.Text(), want)
+	}
+	if want := []string{"", "Recursion", "Base case"}; !slices.Equal(sectionNames(parsed.Sections), want) {
+		t.Errorf("parseDOCX() sections = %q, want %q", sectionNames(parsed.Sections), want)
+	}
+	if parsed.Metadata["title"] != "Recursion" {
+		t.Errorf("parseDOCX() metadata = %v", parsed.Metadata)
+	}
+}
+
+func TestParseODT(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>
+<text:h text:outline-level="1">Recursion</text:h>
+<text:p>A function<text:s text:c="2"/>that <text:span>calls</text:span> itself.</text:p>
+<text:h text:outline-level="2">Base case</text:h>
+<text:p>Stops<text:line-break/>here.</text:p>
+</office:text></office:body></office:document-content>`
+	meta := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><office:meta><dc:title>Recursion</dc:title></office:meta></office:document-meta>`
+
+	parsed, err := parseODT(buildOffice(t, "mimetype", "application/vnd.oasis.opendocument.text", "content.xml", content, "meta.xml", meta))
+	if err != nil {
+		t.Fatalf("parseODT() error = %v", err)
+	}
+
+	if want := "# Recursion\nA function  that calls itself.\n\n## Base case\nStops\nhere."; parsed.Text() != want {
+		t.Errorf("parseODT() text = %q, want %q", parsed.Text(), want)
+	}
+	if parsed.Metadata["title"] != "Recursion" {
+		t.Errorf("parseODT() metadata = %v", parsed.Metadata)
+	}
+}
+
+func TestParsePPTX(t *testing.T) {
+	slide := func(texts string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree><p:sp><p:txBody>` + texts + `</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	presentation := `<?xml version="1.0" encoding="UTF-8"?>
+<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/><p:sldId id="258" r:id="rId4"/></p:sldIdLst></p:presentation>`
+	rels := `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/><Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide3.xml"/></Relationships>`
+	slideRels := `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/></Relationships>`
+
+	content := buildOffice(t,
+		"ppt/presentation.xml", presentation,
+		"ppt/_rels/presentation.xml.rels", rels,
+		// The second file is the first slide of the presentation
+		"ppt/slides/slide1.xml", slide(`<a:p><a:r><a:t>Base case</a:t></a:r></a:p><a:p><a:fld type="slidenum"><a:t>2</a:t></a:fld></a:p>`),
+		"ppt/slides/slide2.xml", slide(`<a:p><a:r><a:t>Recursion</a:t></a:r></a:p><a:p><a:r><a:t>A function</a:t></a:r><a:br/><a:r><a:t>that calls itself</a:t></a:r></a:p>`),
+		"ppt/slides/_rels/slide2.xml.rels", slideRels,
+		"ppt/notesSlides/notesSlide1.xml", slide(`<a:p><a:r><a:t>Start with factorial.</a:t></a:r></a:p>`),
+		"ppt/slides/slide3.xml", slide(``),
+		"docProps/core.xml", officeCore,
+	)
+
+	parsed, err := parsePPTX(content)
+	if err != nil {
+		t.Fatalf("parsePPTX() error = %v", err)
+	}
+
+	if want := "# Slide 1\nRecursion\nA function\nthat calls itself\nNotes:\nStart with factorial.\n\n# Slide 2\nBase case"; parsed.Text() != want {
+		t.Errorf("parsePPTX() text = %q, want %q", parsed.Text(), want)
+	}
+	if want := []string{"Slide 1", "Slide 2"}; !slices.Equal(sectionNames(parsed.Sections), want) {
+		t.Errorf("parsePPTX() sections = %q, want %q", sectionNames(parsed.Sections), want)
+	}
+	if parsed.Metadata["title"] != "Recursion" || parsed.Metadata["pages"] != "3" {
+		t.Errorf("parsePPTX() metadata = %v", parsed.Metadata)
+	}
+}
+
+func TestParseOfficeInvalid(t *testing.T) {
+	for name, parse := range map[string]ParserFunc{"docx": parseDOCX, "odt": parseODT, "pptx": parsePPTX} {
+		if _, err := parse([]byte("not a zip archive")); err == nil {
+			t.Errorf("parse %s of garbage error = nil", name)
+		}
+	}
+
+	if _, err := parseDOCX(buildOffice(t, "word/other.xml", "<w:document/>")); err == nil {
+		t.Errorf("parseDOCX() without word/document.xml error = nil")
+	}
+}
@@ -0,0 +1,159 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Section is a part of the parsed content of a document, such as a page of a
+// PDF, a slide of a presentation or a function of a source file. Page is 0
+// when the format has no pages and Section is the marker of the slide, of the
+// heading or the name of the symbol, if any. The lines are counted from 1 and
+// are only set for source code. Headings is the path of headings the section
+// is under, for the formats structured as a heading tree.
+type Section struct {
+	Text      string   `json:"text"`
+	Page      int      `json:"page,omitempty"`
+	Section   string   `json:"section,omitempty"`
+	StartLine int      `json:"startLine,omitempty"`
+	EndLine   int      `json:"endLine,omitempty"`
+	Headings  []string `json:"headings,omitempty"`
+}
+
+// Content is the text of a document together with its structure.
+type Content struct {
+	Sections []Section         `json:"sections"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (this Content) Text() string {
+	texts := []string{}
+	for _, s := range this.Sections {
+		texts = append(texts, s.Text)
+	}
+
+	return strings.Join(texts, "\n\n")
+}
+
+// Parser extracts the text and the structure of a document.
+type Parser interface {
+	Parse(content []byte) (Content, error)
+}
+
+// ParserFunc allows ordinary functions to be used as parsers.
+type ParserFunc func(content []byte) (Content, error)
+
+func (this ParserFunc) Parse(content []byte) (Content, error) {
+	return this(content)
+}
+
+// ErrUnsupported is returned when no parser is registered for a document.
+var ErrUnsupported = errors.New("content type not supported")
+
+var (
+	contentTypeParsers = map[string]Parser{}
+	extensionTypes     = map[string]string{}
+)
+
+// RegisterContentType makes the parser handle the documents with the given
+// MIME type, replacing the parser registered before for it, if any.
+func RegisterContentType(contentType string, parser Parser) {
+	contentTypeParsers[normaliseContentType(contentType)] = parser
+}
+
+// RegisterExtension makes the documents whose filename has the given
+// extension, such as ".md", be handled as the given MIME type when their own
+// content type is not known.
+func RegisterExtension(extension string, contentType string) {
+	extensionTypes[strings.ToLower(extension)] = normaliseContentType(contentType)
+}
+
+// ExtensionContentType returns the MIME type registered for the extension of
+// the filename, if any.
+func ExtensionContentType(filename string) (string, bool) {
+	contentType, ok := extensionTypes[strings.ToLower(path.Ext(filename))]
+	return contentType, ok
+}
+
+func normaliseContentType(contentType string) string {
+	// Parameters such as the charset are not part of the content type
+	contentType, _, _ = strings.Cut(contentType, ";")
+
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Lookup returns the parser for a document, trying its MIME type first and
+// then the extension of its filename.
+func Lookup(filename string, contentType string) (Parser, error) {
+	if parser, ok := contentTypeParsers[normaliseContentType(contentType)]; ok {
+		return parser, nil
+	}
+
+	if contentType, ok := ExtensionContentType(filename); ok {
+		if parser, ok := contentTypeParsers[contentType]; ok {
+			return parser, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s (%s)", ErrUnsupported, filename, contentType)
+}
+
+// Supported reports whether a parser is registered for the document.
+func Supported(filename string, contentType string) bool {
+	_, err := Lookup(filename, contentType)
+	return err == nil
+}
+
+// Parse extracts the content of a document with the registered parsers.
+func Parse(filename string, contentType string, content []byte) (Content, error) {
+	parser, err := Lookup(filename, contentType)
+	if err != nil {
+		return Content{}, err
+	}
+
+	return parser.Parse(content)
+}
+
+func parsePlainText(content []byte) (Content, error) {
+	return Content{Sections: []Section{{Text: string(content)}}}, nil
+}
+
+func init() {
+	RegisterContentType("text/plain", ParserFunc(parsePlainText))
+	RegisterExtension(".txt", "text/plain")
+	RegisterExtension(".text", "text/plain")
+	RegisterContentType("text/markdown", ParserFunc(parseMarkdown))
+	RegisterExtension(".md", "text/markdown")
+	RegisterExtension(".markdown", "text/markdown")
+	RegisterContentType("text/x-rst", ParserFunc(parseRST))
+	RegisterExtension(".rst", "text/x-rst")
+
+	RegisterContentType("application/pdf", ParserFunc(parsePDF))
+	RegisterExtension(".pdf", "application/pdf")
+
+	RegisterContentType("text/html", ParserFunc(parseHTML))
+	RegisterExtension(".html", "text/html")
+	RegisterExtension(".htm", "text/html")
+	RegisterContentType("application/xhtml+xml", ParserFunc(parseHTML))
+	RegisterExtension(".xhtml", "application/xhtml+xml")
+
+	RegisterContentType("text/x-go", ParserFunc(parseGo))
+	RegisterExtension(".go", "text/x-go")
+	RegisterContentType("text/x-python", ParserFunc(parsePython))
+	RegisterExtension(".py", "text/x-python")
+	RegisterContentType("text/x-c", ParserFunc(parseC))
+	RegisterExtension(".c", "text/x-c")
+	RegisterExtension(".h", "text/x-c")
+
+	RegisterContentType("application/x-ipynb+json", NotebookParser{})
+	RegisterExtension(".ipynb", "application/x-ipynb+json")
+
+	RegisterContentType("application/vnd.openxmlformats-officedocument.wordprocessingml.document", ParserFunc(parseDOCX))
+	RegisterExtension(".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+	RegisterContentType("application/vnd.openxmlformats-officedocument.presentationml.presentation", ParserFunc(parsePPTX))
+	RegisterExtension(".pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation")
+	RegisterContentType("application/vnd.oasis.opendocument.text", ParserFunc(parseODT))
+	RegisterExtension(".odt", "application/vnd.oasis.opendocument.text")
+}
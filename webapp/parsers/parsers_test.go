@@ -0,0 +1,69 @@
+package parsers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		filename    string
+		contentType string
+		supported   bool
+	}{
+		{filename: "notes.txt", contentType: "text/plain", supported: true},
+		{filename: "notes", contentType: "text/plain; charset=utf-8", supported: true},
+		{filename: "notes", contentType: "TEXT/MARKDOWN", supported: true},
+		{filename: "notes.MD", contentType: "application/octet-stream", supported: true},
+		{filename: "slides.pptx", contentType: "", supported: true},
+		{filename: "photo.png", contentType: "image/png"},
+		{filename: "notes", contentType: ""},
+	}
+
+	for _, tt := range tests {
+		_, err := Lookup(tt.filename, tt.contentType)
+		if tt.supported && err != nil {
+			t.Errorf("Lookup(%q, %q) error = %v", tt.filename, tt.contentType, err)
+		}
+		if !tt.supported && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Lookup(%q, %q) error = %v, want %v", tt.filename, tt.contentType, err, ErrUnsupported)
+		}
+		if got := Supported(tt.filename, tt.contentType); got != tt.supported {
+			t.Errorf("Supported(%q, %q) = %v, want %v", tt.filename, tt.contentType, got, tt.supported)
+		}
+	}
+}
+
+func TestExtensionContentType(t *testing.T) {
+	tests := []struct {
+		filename    string
+		contentType string
+		ok          bool
+	}{
+		{filename: "notes.md", contentType: "text/markdown", ok: true},
+		{filename: "dir.v2/Notes.RST", contentType: "text/x-rst", ok: true},
+		{filename: "slides.PPTX", contentType: "application/vnd.openxmlformats-officedocument.presentationml.presentation", ok: true},
+		{filename: "main.h", contentType: "text/x-c", ok: true},
+		{filename: "notes.md.bak"},
+		{filename: "README"},
+	}
+
+	for _, tt := range tests {
+		contentType, ok := ExtensionContentType(tt.filename)
+		if contentType != tt.contentType || ok != tt.ok {
+			t.Errorf("ExtensionContentType(%q) = %q, %v, want %q, %v", tt.filename, contentType, ok, tt.contentType, tt.ok)
+		}
+	}
+}
+
+func TestContentText(t *testing.T) {
+	content := Content{Sections: []Section{{Text: "Page one"}, {Text: "Page two"}}}
+	if got := content.Text(); got != "Page one\n\nPage two" {
+		t.Errorf("Text() = %q", got)
+	}
+
+	parsed, err := Parse("notes.txt", "text/plain", []byte("Plain text."))
+	if err != nil || parsed.Text() != "Plain text." {
+		t.Errorf("Parse() = %q, %v", parsed.Text(), err)
+	}
+}
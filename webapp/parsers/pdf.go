@@ -0,0 +1,63 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// parsePDF extracts the text of every page of the pdf, keeping the page
+// numbers, along with the page count and the title of the document info.
+// Pages that fail to parse are skipped. The pdf library panics on malformed
+// files, which is turned into an error.
+func parsePDF(content []byte) (parsed Content, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			parsed, err = Content{}, fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return
+	}
+
+	fonts := map[string]*pdf.Font{}
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, pageErr := page.GetPlainText(fonts)
+		if pageErr != nil {
+			slog.Warn("Error extracting the text of a pdf page", "page", i, "error", pageErr.Error())
+			continue
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		parsed.Sections = append(parsed.Sections, Section{Text: text, Page: i})
+	}
+
+	parsed.Metadata = map[string]string{"pages": strconv.Itoa(reader.NumPage())}
+	if title := strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text()); title != "" {
+		parsed.Metadata["title"] = title
+	}
+
+	return
+}
@@ -0,0 +1,92 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a pdf with one page per text and the title in its info.
+func buildPDF(title string, pages ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Title (%s) >>", title),
+	}
+
+	kids := []string{}
+	for _, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	out := &bytes.Buffer{}
+	out.WriteString("%PDF-1.4\n")
+
+	offsets := []int{}
+	for i, o := range objects {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return out.Bytes()
+}
+
+func TestParsePDF(t *testing.T) {
+	valid := buildPDF("Quarterly report", "First page", "Second page")
+
+	corrupt := bytes.Replace(valid, []byte("3 0 obj"), []byte("3 0 xyz"), 1)
+
+	tests := []struct {
+		name    string
+		content []byte
+		pages   []string
+		wantErr bool
+	}{
+		{name: "valid", content: valid, pages: []string{"First page", "Second page"}},
+		{name: "truncated", content: valid[:len(valid)/2], wantErr: true},
+		{name: "corrupt object", content: corrupt, wantErr: true},
+		{name: "garbage", content: append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 64)...), wantErr: true},
+		{name: "empty", content: []byte{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parsePDF(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePDF() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePDF() error = %v", err)
+			}
+
+			if len(parsed.Sections) != len(tt.pages) {
+				t.Fatalf("parsePDF() got %d sections, want %d", len(parsed.Sections), len(tt.pages))
+			}
+			for i, s := range parsed.Sections {
+				if s.Page != i+1 || !strings.Contains(s.Text, tt.pages[i]) {
+					t.Errorf("section %d = page %d %q, want page %d %q", i, s.Page, s.Text, i+1, tt.pages[i])
+				}
+			}
+
+			if parsed.Metadata["title"] != "Quarterly report" || parsed.Metadata["pages"] != "2" {
+				t.Errorf("parsePDF() metadata = %v", parsed.Metadata)
+			}
+		})
+	}
+}
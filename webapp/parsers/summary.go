@@ -0,0 +1,106 @@
+package parsers
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Summary describes a parsed document, so that the documents can be listed
+// without reading their content.
+type Summary struct {
+	Title     string
+	Language  string
+	PageCount int
+	WordCount int
+}
+
+// Summarize counts the pages and the words of the content and detects its
+// language. The page count is the one given by the parser, such as the pages
+// of a pdf or the slides of a presentation, or else the last page numbered in
+// the sections.
+func Summarize(content Content) Summary {
+	summary := Summary{Title: content.Metadata["title"]}
+
+	summary.PageCount, _ = strconv.Atoi(content.Metadata["pages"])
+	for _, s := range content.Sections {
+		if s.Page > summary.PageCount {
+			summary.PageCount = s.Page
+		}
+	}
+
+	words := []string{}
+	for _, s := range content.Sections {
+		for _, field := range strings.Fields(s.Text) {
+			if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) < 0 {
+				continue
+			}
+
+			summary.WordCount++
+			if len(words) < languageSampleSize {
+				words = append(words, field)
+			}
+		}
+	}
+
+	summary.Language = DetectLanguage(words)
+
+	return summary
+}
+
+// languageSampleSize is the number of words looked at to detect the language.
+const languageSampleSize = 2000
+
+// stopWords are frequent words that are distinctive enough of a language,
+// which are counted to tell the language of a text.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "with", "for", "as", "was", "on", "are", "be", "this", "by", "not", "or", "have", "from", "which", "you", "they", "an", "we", "can", "their", "there", "been", "were", "would"},
+	"fr": {"le", "la", "les", "des", "et", "est", "une", "du", "dans", "qui", "pour", "pas", "sur", "au", "avec", "ce", "il", "ne", "sont", "nous", "vous", "mais", "ou", "plus", "cette", "aux", "leur", "été", "être", "comme"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "eine", "zu", "auf", "sich", "dem", "für", "von", "auch", "es", "wird", "sind", "werden", "bei", "aus", "nach", "wie", "einer", "oder", "dass", "noch", "über"},
+	"es": {"el", "los", "las", "del", "que", "en", "una", "por", "con", "para", "es", "se", "lo", "como", "más", "pero", "sus", "le", "ya", "fue", "este", "ha", "son", "entre", "cuando", "muy", "sin", "sobre", "también", "hay"},
+	"it": {"il", "della", "di", "che", "è", "per", "una", "sono", "non", "con", "gli", "nel", "alla", "anche", "come", "del", "più", "questo", "ma", "dei", "delle", "nella", "essere", "loro", "ha", "stato", "tra", "quando", "lo", "si"},
+	"pt": {"os", "das", "não", "uma", "com", "para", "que", "em", "dos", "ao", "mais", "como", "mas", "foi", "ele", "pelo", "pela", "são", "também", "à", "seu", "sua", "ou", "quando", "muito", "nos", "já", "está", "isso", "entre"},
+	"nl": {"de", "het", "een", "van", "en", "is", "dat", "niet", "zijn", "op", "te", "met", "voor", "er", "maar", "om", "ook", "als", "bij", "nog", "wordt", "uit", "dan", "door", "naar", "werd", "heeft", "deze", "worden", "geen"},
+}
+
+var stopWordLanguages = func() map[string][]string {
+	languages := map[string][]string{}
+	for language, words := range stopWords {
+		for _, w := range words {
+			languages[w] = append(languages[w], language)
+		}
+	}
+
+	return languages
+}()
+
+// DetectLanguage returns the ISO 639-1 code of the language of the words, or
+// an empty string when the words are too few or too ambiguous to tell, as for
+// source code.
+func DetectLanguage(words []string) string {
+	scores := map[string]int{}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }))
+		for _, language := range stopWordLanguages[w] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for language, score := range scores {
+		switch {
+		case score > bestScore || (score == bestScore && language < best):
+			best, bestScore, secondScore = language, score, max(bestScore, secondScore)
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+
+	// The stop words must be a noticeable part of the text, and clearly more
+	// of one language than of the others
+	if bestScore < 5 || bestScore*20 < len(words) || bestScore*4 < secondScore*5 {
+		return ""
+	}
+
+	return best
+}
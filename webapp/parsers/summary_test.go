@@ -0,0 +1,78 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		content Content
+		want    Summary
+	}{
+		{
+			name:    "empty",
+			content: Content{},
+			want:    Summary{},
+		},
+		{
+			name: "pages of the parser",
+			content: Content{
+				Sections: []Section{{Text: "Slide one", Page: 1}, {Text: "Slide three", Page: 3}},
+				Metadata: map[string]string{"title": "Recursion", "pages": "5"},
+			},
+			want: Summary{Title: "Recursion", PageCount: 5, WordCount: 4},
+		},
+		{
+			name:    "pages of the sections",
+			content: Content{Sections: []Section{{Text: "One", Page: 1}, {Text: "Two", Page: 2}}},
+			want:    Summary{PageCount: 2, WordCount: 2},
+		},
+		{
+			name:    "punctuation is not counted",
+			content: Content{Sections: []Section{{Text: "Recursion - see: recursion... 42 -> ##"}}},
+			want:    Summary{WordCount: 4},
+		},
+		{
+			name: "language",
+			content: Content{Sections: []Section{
+				{Text: "The function calls itself until it reaches the base case, and the result of each call is returned to the caller."},
+				{Text: "This is the way it is done in most of the courses that we have seen."},
+			}},
+			want: Summary{Language: "en", WordCount: 37},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.content); got != tt.want {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "english", text: "The tide rises twice a day because the Moon pulls on the oceans, and the Earth turns under the bulges of water that it makes.", want: "en"},
+		{name: "french", text: "La marée monte deux fois par jour parce que la Lune attire les océans, et la Terre tourne sous les renflements d'eau qu'elle crée dans le monde.", want: "fr"},
+		{name: "german", text: "Die Flut steigt zweimal am Tag, weil der Mond an den Ozeanen zieht und sich die Erde unter dem Wasser dreht, das er auf der Erde anhebt.", want: "de"},
+		{name: "spanish", text: "La marea sube dos veces al día porque la Luna atrae los océanos, y la Tierra gira bajo las masas de agua que se forman en el mar con el tiempo.", want: "es"},
+		{name: "too few words", text: "The Moon and the tides.", want: ""},
+		{name: "source code", text: strings.Repeat("func main() { x := f(y); return x } ", 10), want: ""},
+		{name: "no words", text: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(strings.Fields(tt.text)); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type AnswersRepository interface {
+	GetAnswer(c context.Context, id uuid.UUID) (models.SearchAnswer, error)
+	CreateAnswer(c context.Context, answer models.SearchAnswer) (models.SearchAnswer, error)
+	SaveFeedback(c context.Context, feedback models.AnswerFeedback) (models.AnswerFeedback, error)
+	GetFeedbackReport(c context.Context, slug uuid.UUID, limit int) ([]models.AnswerFeedbackReport, error)
+}
+
+type answersRepository struct {
+	db *bun.DB
+}
+
+func NewAnswersRepository(db *bun.DB) AnswersRepository {
+	return answersRepository{db}
+}
+
+func (this answersRepository) GetAnswer(c context.Context, id uuid.UUID) (answer models.SearchAnswer, err error) {
+	err = this.db.NewSelect().Model(&answer).Where("id = ?", id).Scan(c)
+
+	return
+}
+
+func (this answersRepository) CreateAnswer(c context.Context, answer models.SearchAnswer) (models.SearchAnswer, error) {
+	_, err := this.db.NewInsert().Model(&answer).Exec(c)
+
+	return answer, err
+}
+
+func (this answersRepository) SaveFeedback(c context.Context, feedback models.AnswerFeedback) (models.AnswerFeedback, error) {
+	_, err := this.db.NewInsert().
+		Model(&feedback).
+		On("CONFLICT (answer_id, user_id) DO UPDATE").
+		Set("rating = EXCLUDED.rating").
+		Set("comment = EXCLUDED.comment").
+		Set("created_at = EXCLUDED.created_at").
+		Returning("*").
+		Exec(c)
+
+	return feedback, err
+}
+
+// GetFeedbackReport returns the rated answers of a post, worst rated first.
+func (this answersRepository) GetFeedbackReport(c context.Context, slug uuid.UUID, limit int) ([]models.AnswerFeedbackReport, error) {
+	report := []models.AnswerFeedbackReport{}
+
+	err := this.db.NewSelect().
+		TableExpr("search_answers AS sa").
+		ColumnExpr("sa.id AS answer_id, sa.query, sa.response, sa.model, sa.created_at").
+		ColumnExpr("SUM(af.rating) AS score").
+		ColumnExpr("COUNT(*) FILTER (WHERE af.rating > 0) AS up").
+		ColumnExpr("COUNT(*) FILTER (WHERE af.rating < 0) AS down").
+		ColumnExpr("ARRAY_REMOVE(ARRAY_AGG(NULLIF(af.comment, '')), NULL) AS comments").
+		Join("JOIN answer_feedback AS af").
+		JoinOn("af.answer_id = sa.id").
+		Where("sa.post_slug = ?", slug).
+		Group("sa.id").
+		Order("score ASC", "down DESC", "sa.created_at DESC").
+		Limit(limit).
+		Scan(c, &report)
+
+	return report, err
+}
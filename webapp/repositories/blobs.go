@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"webapp-go/webapp/blobs"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// contentKeys returns the blob keys of the documents of a post, or of a single
+// document when the id is not nil, along with the ones of their revisions.
+func contentKeys(c context.Context, db bun.IDB, slug uuid.UUID, id uuid.UUID) ([]string, error) {
+	documents := db.NewSelect().Model((*models.Document)(nil)).Column("content_key").Where("post_slug = ?", slug).Where("content_key != ''")
+	revisions := db.NewSelect().Model((*models.DocumentRevision)(nil)).Column("content_key").Where("post_slug = ?", slug).Where("content_key != ''")
+	if id != uuid.Nil {
+		documents = documents.Where("id = ?", id)
+		revisions = revisions.Where("document_id = ?", id)
+	}
+
+	keys := []string{}
+	err := documents.Union(revisions).Scan(c, &keys)
+
+	return keys, err
+}
+
+// deleteBlobs removes the blobs of deleted documents. A blob that cannot be
+// removed is only logged, as the documents are already gone.
+func deleteBlobs(c context.Context, store blobs.BlobStore, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(c, key); err != nil && !errors.Is(err, blobs.ErrNotFound) {
+			slog.Error("Error deleting blob", "key", key, "error", err.Error())
+		}
+	}
+}
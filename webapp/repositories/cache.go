@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SearchCacheRepository interface {
+	GetEntry(c context.Context, slug uuid.UUID, query string, limit int, version int) (models.SearchCacheEntry, error)
+	SaveEntry(c context.Context, entry models.SearchCacheEntry) (models.SearchCacheEntry, error)
+	DeleteEntriesFor(c context.Context, slug uuid.UUID) (uuid.UUID, error)
+	DeleteExpiredEntries(c context.Context) error
+}
+
+type searchCacheRepository struct {
+	db *bun.DB
+}
+
+func NewSearchCacheRepository(db *bun.DB) SearchCacheRepository {
+	return searchCacheRepository{db}
+}
+
+func (this searchCacheRepository) GetEntry(c context.Context, slug uuid.UUID, query string, limit int, version int) (entry models.SearchCacheEntry, err error) {
+	err = this.db.NewSelect().
+		Model(&entry).
+		Where("post_slug = ?", slug).
+		Where("query = ?", query).
+		Where("search_limit = ?", limit).
+		Where("document_version = ?", version).
+		Where("expires_at > ?", time.Now()).
+		Scan(c)
+
+	return
+}
+
+func (this searchCacheRepository) SaveEntry(c context.Context, entry models.SearchCacheEntry) (models.SearchCacheEntry, error) {
+	_, err := this.db.NewInsert().
+		Model(&entry).
+		On("CONFLICT (post_slug, query, search_limit, document_version) DO UPDATE").
+		Set("scores = EXCLUDED.scores").
+		Set("response = EXCLUDED.response").
+		Set("created_at = EXCLUDED.created_at").
+		Set("expires_at = EXCLUDED.expires_at").
+		Exec(c)
+
+	return entry, err
+}
+
+func (this searchCacheRepository) DeleteEntriesFor(c context.Context, slug uuid.UUID) (uuid.UUID, error) {
+	_, err := this.db.NewDelete().Model((*models.SearchCacheEntry)(nil)).Where("post_slug = ?", slug).Exec(c)
+
+	return slug, err
+}
+
+func (this searchCacheRepository) DeleteExpiredEntries(c context.Context) error {
+	_, err := this.db.NewDelete().Model((*models.SearchCacheEntry)(nil)).Where("expires_at <= ?", time.Now()).Exec(c)
+
+	return err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"webapp-go/webapp/blobs"
 	"webapp-go/webapp/models"
 
 	"github.com/google/uuid"
@@ -11,25 +12,36 @@ import (
 type DocumentsRepository interface {
 	GetDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (models.Document, error)
 	GetDocuments(c context.Context, slug uuid.UUID) ([]models.Document, error)
-	CreateDocument(c context.Context, document models.Document) (models.Document, error)
-	UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document) (models.Document, error)
+	GetContent(c context.Context, document models.Document) ([]byte, error)
+	GetContentHashes(c context.Context) (map[string]string, error)
+	SetIndexStatus(c context.Context, document models.Document) error
+	CreateDocument(c context.Context, document models.Document, authorId uuid.UUID) (models.Document, error)
+	UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document, authorId uuid.UUID) (models.Document, error)
 	DeleteDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (uuid.UUID, error)
+	GetRevisions(c context.Context, slug uuid.UUID, id uuid.UUID) ([]models.DocumentRevision, error)
+	GetRevision(c context.Context, slug uuid.UUID, id uuid.UUID, revisionId uuid.UUID) (models.DocumentRevision, error)
 }
 
 type documentsRepository struct {
-	db *bun.DB
+	db    *bun.DB
+	blobs blobs.BlobStore
 }
 
-func NewDocumentsRepository(db *bun.DB) DocumentsRepository {
-	return documentsRepository{db}
+// NewDocumentsRepository keeps the rows of the documents in the database and
+// their content in the blob store.
+func NewDocumentsRepository(db *bun.DB, blobs blobs.BlobStore) DocumentsRepository {
+	return documentsRepository{db, blobs}
 }
 
+// GetDocument returns the document without its content, which is read with
+// GetContent.
 func (this documentsRepository) GetDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (document models.Document, err error) {
 	err = this.db.NewSelect().Model(&document).Where("post_slug = ?", slug).Where("id = ?", id).Scan(c)
 
 	return
 }
 
+// GetDocuments returns the documents of the post without their content.
 func (this documentsRepository) GetDocuments(c context.Context, slug uuid.UUID) (documents []models.Document, err error) {
 	documents = []models.Document{}
 
@@ -40,23 +52,212 @@ func (this documentsRepository) GetDocuments(c context.Context, slug uuid.UUID)
 	return
 }
 
-func (this documentsRepository) CreateDocument(c context.Context, document models.Document) (models.Document, error) {
-	_, err := this.db.NewInsert().Model(&document).Exec(c)
+// GetContent reads the content of the document from the blob store.
+func (this documentsRepository) GetContent(c context.Context, document models.Document) ([]byte, error) {
+	if document.ContentKey == "" {
+		return []byte{}, nil
+	}
+
+	return this.blobs.Get(c, document.ContentKey)
+}
+
+// GetContentHashes returns the hash of every blob used by the documents and
+// their revisions, by key.
+func (this documentsRepository) GetContentHashes(c context.Context) (map[string]string, error) {
+	rows := []struct {
+		Key  string `bun:"content_key"`
+		Hash string `bun:"content_hash"`
+	}{}
+
+	documents := this.db.NewSelect().Model((*models.Document)(nil)).Column("content_key", "content_hash").Where("content_key != ''")
+	revisions := this.db.NewSelect().Model((*models.DocumentRevision)(nil)).Column("content_key", "content_hash").Where("content_key != ''")
+
+	if err := documents.Union(revisions).Scan(c, &rows); err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]string{}
+	for _, r := range rows {
+		hashes[r.Key] = r.Hash
+	}
+
+	return hashes, nil
+}
+
+// putContent stores the content of the document under a new key.
+func (this documentsRepository) putContent(c context.Context, document *models.Document) error {
+	document.ContentKey = models.NewContentKey(document.PostSlug)
+	document.ContentHash = models.ContentHash(document.Content)
+	document.Size = int64(len(document.Content))
+
+	return this.blobs.Put(c, document.ContentKey, document.Content)
+}
+
+// CreateDocument stores the content and inserts the document along with its
+// first revision.
+func (this documentsRepository) CreateDocument(c context.Context, document models.Document, authorId uuid.UUID) (models.Document, error) {
+	if err := this.putContent(c, &document); err != nil {
+		return document, err
+	}
+
+	err := this.db.RunInTx(c, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&document).Exec(ctx); err != nil {
+			return err
+		}
+
+		revision := models.NewDocumentRevision(document, authorId)
+		_, err := tx.NewInsert().Model(&revision).Exec(ctx)
+
+		return err
+	})
+	if err != nil {
+		deleteBlobs(c, this.blobs, []string{document.ContentKey})
+	}
 
 	return document, err
 }
 
-func (this documentsRepository) UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document) (models.Document, error) {
+// UpdateDocument updates the non zero fields of the document, and records a
+// revision by the author when the content changes.
+func (this documentsRepository) UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document, authorId uuid.UUID) (models.Document, error) {
 	document.PostSlug = slug
 	document.ID = id
 
-	_, err := this.db.NewUpdate().Model(&document).OmitZero().WherePK().Exec(c)
+	// A document given with its content hash carries its content even when
+	// it is empty, as a restored revision does
+	changed := len(document.Content) > 0 || document.ContentHash != ""
+	newKey := ""
+	if changed {
+		if err := this.putContent(c, &document); err != nil {
+			return document, err
+		}
+		newKey = document.ContentKey
+	}
+
+	recorded := false
+	err := this.db.RunInTx(c, nil, func(ctx context.Context, tx bun.Tx) error {
+		existing := models.Document{}
+		err := tx.NewSelect().Model(&existing).Where("post_slug = ?", slug).Where("id = ?", id).For("UPDATE").Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		// The blob of the same content is kept, so that the document and its
+		// last revision keep sharing it
+		if changed && document.ContentHash == existing.ContentHash {
+			document.ContentKey = existing.ContentKey
+		}
+
+		if _, err := tx.NewUpdate().Model(&document).OmitZero().WherePK().Exec(ctx); err != nil {
+			return err
+		}
+
+		// The description of the new content replaces the previous one, even
+		// where it is empty, and the content waits to be indexed again
+		if changed {
+			document.IndexStatus = models.INDEX_PENDING
+			document.IndexError = ""
+			document.Warnings = []models.DocumentWarning{}
+			_, err := tx.NewUpdate().Model(&document).Column(describedColumns...).Column("size", "index_status", "index_error", "warnings").WherePK().Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !changed || document.ContentHash == existing.ContentHash {
+			return nil
+		}
+
+		updated := existing
+		updated.ContentKey = document.ContentKey
+		updated.ContentHash = document.ContentHash
+		updated.Size = document.Size
+		if document.Filename != "" {
+			updated.Filename = document.Filename
+		}
+		if document.ContentType != "" {
+			updated.ContentType = document.ContentType
+		}
+
+		revision := models.NewDocumentRevision(updated, authorId)
+		_, err = tx.NewInsert().Model(&revision).Exec(ctx)
+		recorded = err == nil
+
+		return err
+	})
+
+	// The new blob is only kept when a revision refers to it
+	if changed && !recorded {
+		deleteBlobs(c, this.blobs, []string{newKey})
+	}
 
 	return document, err
 }
 
+// describedColumns are the columns set from the parsed content by Describe.
+var describedColumns = []string{"title", "language", "page_count", "word_count", "metadata"}
+
+// SetIndexStatus saves the indexing status of the document, along with the
+// description of the content that was indexed and the warnings about its
+// duplicates. Nothing is saved when the content has changed since, as it
+// waits to be indexed again.
+func (this documentsRepository) SetIndexStatus(c context.Context, document models.Document) error {
+	_, err := this.db.NewUpdate().
+		Model(&document).
+		Column(describedColumns...).
+		Column("index_status", "index_error", "warnings").
+		WherePK().
+		Where("content_hash = ?", document.ContentHash).
+		Exec(c)
+
+	return err
+}
+
+// DeleteDocument deletes the document, its revisions and their blobs.
 func (this documentsRepository) DeleteDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (uuid.UUID, error) {
-	_, err := this.db.NewDelete().Model(&models.Document{}).Where("post_slug = ?", slug).Where("id = ?", id).Exec(c)
+	keys, err := contentKeys(c, this.db, slug, id)
+	if err != nil {
+		return id, err
+	}
+
+	_, err = this.db.NewDelete().Model(&models.Document{}).Where("post_slug = ?", slug).Where("id = ?", id).Exec(c)
+	if err != nil {
+		return id, err
+	}
+
+	deleteBlobs(c, this.blobs, keys)
 
-	return id, err
+	return id, nil
+}
+
+// GetRevisions lists the revisions of a document from the newest, without
+// their content.
+func (this documentsRepository) GetRevisions(c context.Context, slug uuid.UUID, id uuid.UUID) (revisions []models.DocumentRevision, err error) {
+	revisions = []models.DocumentRevision{}
+
+	err = this.db.NewSelect().
+		Model(&revisions).
+		Where("post_slug = ?", slug).
+		Where("document_id = ?", id).
+		Order("created_at DESC").
+		Scan(c)
+
+	return
+}
+
+// GetRevision returns a revision of a document along with its content.
+func (this documentsRepository) GetRevision(c context.Context, slug uuid.UUID, id uuid.UUID, revisionId uuid.UUID) (revision models.DocumentRevision, err error) {
+	err = this.db.NewSelect().
+		Model(&revision).
+		Where("post_slug = ?", slug).
+		Where("document_id = ?", id).
+		Where("id = ?", revisionId).
+		Scan(c)
+	if err != nil || revision.ContentKey == "" {
+		return
+	}
+
+	revision.Content, err = this.blobs.Get(c, revision.ContentKey)
+
+	return
 }
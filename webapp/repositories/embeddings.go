@@ -10,9 +10,12 @@ import (
 
 type EmbeddingsRepository interface {
 	GetSimilarEmbeddings(c context.Context, slug uuid.UUID, embedding []float32, limit int) ([]models.DocumentScore, error)
+	GetSimilarDocuments(c context.Context, slug uuid.UUID, id uuid.UUID, embedding []float32, threshold float32, limit int) ([]models.DocumentWarning, error)
 	CreateEmbedding(c context.Context, embedding models.DocumentEmbedding) (models.DocumentEmbedding, error)
-	UpdateEmbeddingFor(c context.Context, documentID uuid.UUID, embedding models.DocumentEmbedding) (models.DocumentEmbedding, error)
 	DeleteEmbeddingFor(c context.Context, documentID uuid.UUID) (uuid.UUID, error)
+	GetCachedEmbedding(c context.Context, hash string, model string) (models.EmbeddingCacheEntry, error)
+	SaveCachedEmbedding(c context.Context, entry models.EmbeddingCacheEntry) (models.EmbeddingCacheEntry, error)
+	SaveEmbeddings(c context.Context, documentIDs []uuid.UUID, embeddings []models.DocumentEmbedding, cacheEntries []models.EmbeddingCacheEntry) error
 }
 
 type embeddingsRepository struct {
@@ -28,26 +31,43 @@ func (this embeddingsRepository) GetSimilarEmbeddings(c context.Context, slug uu
 
 	err := this.db.NewSelect().
 		Table("document_embeddings").
-		Column("document_embeddings.document_id").
+		Column("document_embeddings.document_id", "document_embeddings.chunk_index", "document_embeddings.content", "document_embeddings.page", "document_embeddings.section", "document_embeddings.start_line", "document_embeddings.end_line").
 		ColumnExpr("1 - (embeddings <=> ?) AS score", embedding).
 		Join("JOIN documents as d").
 		JoinOn("document_embeddings.document_id = d.id").
 		Where("post_slug = ?", slug).
-		Order("score").
+		OrderExpr("score DESC").
 		Limit(limit).
 		Scan(c, &scores)
 
 	return scores, err
 }
 
-func (this embeddingsRepository) CreateEmbedding(c context.Context, embedding models.DocumentEmbedding) (models.DocumentEmbedding, error) {
-	_, err := this.db.NewInsert().Model(&embedding).Exec(c)
+// GetSimilarDocuments returns the other documents of the post whose mean
+// embedding is at least as similar to the given one as the threshold, from
+// the most similar.
+func (this embeddingsRepository) GetSimilarDocuments(c context.Context, slug uuid.UUID, id uuid.UUID, embedding []float32, threshold float32, limit int) ([]models.DocumentWarning, error) {
+	documents := []models.DocumentWarning{}
 
-	return embedding, err
+	err := this.db.NewSelect().
+		Table("document_embeddings").
+		Column("document_embeddings.document_id", "d.filename").
+		ColumnExpr("1 - (avg(embeddings) <=> ?) AS similarity", embedding).
+		Join("JOIN documents as d").
+		JoinOn("document_embeddings.document_id = d.id").
+		Where("post_slug = ?", slug).
+		Where("document_embeddings.document_id != ?", id).
+		Group("document_embeddings.document_id", "d.filename").
+		Having("1 - (avg(embeddings) <=> ?) >= ?", embedding, threshold).
+		OrderExpr("similarity DESC").
+		Limit(limit).
+		Scan(c, &documents)
+
+	return documents, err
 }
 
-func (this embeddingsRepository) UpdateEmbeddingFor(c context.Context, documentID uuid.UUID, embedding models.DocumentEmbedding) (models.DocumentEmbedding, error) {
-	_, err := this.db.NewUpdate().Model(&embedding).OmitZero().Where("document_id = ?", documentID).Exec(c)
+func (this embeddingsRepository) CreateEmbedding(c context.Context, embedding models.DocumentEmbedding) (models.DocumentEmbedding, error) {
+	_, err := this.db.NewInsert().Model(&embedding).Exec(c)
 
 	return embedding, err
 }
@@ -57,3 +77,50 @@ func (this embeddingsRepository) DeleteEmbeddingFor(c context.Context, id uuid.U
 
 	return id, err
 }
+
+func (this embeddingsRepository) GetCachedEmbedding(c context.Context, hash string, model string) (entry models.EmbeddingCacheEntry, err error) {
+	err = this.db.NewSelect().Model(&entry).Where("content_hash = ?", hash).Where("model = ?", model).Scan(c)
+
+	return
+}
+
+func (this embeddingsRepository) SaveCachedEmbedding(c context.Context, entry models.EmbeddingCacheEntry) (models.EmbeddingCacheEntry, error) {
+	_, err := this.db.NewInsert().Model(&entry).On("CONFLICT (content_hash, model) DO NOTHING").Exec(c)
+
+	return entry, err
+}
+
+// SaveEmbeddings replaces the embeddings of the chunks of the documents and
+// stores the newly generated vectors in the embedding cache, in one transaction.
+func (this embeddingsRepository) SaveEmbeddings(c context.Context, documentIDs []uuid.UUID, embeddings []models.DocumentEmbedding, cacheEntries []models.EmbeddingCacheEntry) error {
+	return this.db.RunInTx(c, nil, func(ctx context.Context, tx bun.Tx) error {
+		if len(documentIDs) > 0 {
+			_, err := tx.NewDelete().
+				Model((*models.DocumentEmbedding)(nil)).
+				Where("document_id IN (?)", bun.In(documentIDs)).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(embeddings) > 0 {
+			_, err := tx.NewInsert().Model(&embeddings).Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(cacheEntries) > 0 {
+			_, err := tx.NewInsert().
+				Model(&cacheEntries).
+				On("CONFLICT (content_hash, model) DO NOTHING").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
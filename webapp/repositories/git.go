@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type GitSourcesRepository interface {
+	GetSource(c context.Context, slug uuid.UUID) (models.GitSource, error)
+	GetSources(c context.Context) ([]models.GitSource, error)
+	SaveSource(c context.Context, source models.GitSource) (models.GitSource, error)
+	DeleteSource(c context.Context, slug uuid.UUID) (uuid.UUID, error)
+	SetSynced(c context.Context, slug uuid.UUID, commit string) error
+	CreateDelivery(c context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, bool, error)
+	UpdateDelivery(c context.Context, delivery models.WebhookDelivery) error
+	GetDeliveries(c context.Context, slug uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+}
+
+type gitSourcesRepository struct {
+	db *bun.DB
+}
+
+func NewGitSourcesRepository(db *bun.DB) GitSourcesRepository {
+	return gitSourcesRepository{db}
+}
+
+func (this gitSourcesRepository) GetSource(c context.Context, slug uuid.UUID) (source models.GitSource, err error) {
+	err = this.db.NewSelect().Model(&source).Where("post_slug = ?", slug).Scan(c)
+
+	return
+}
+
+func (this gitSourcesRepository) GetSources(c context.Context) (sources []models.GitSource, err error) {
+	sources = []models.GitSource{}
+
+	err = this.db.NewSelect().Model(&sources).Scan(c)
+
+	return
+}
+
+// SaveSource links the post to the repository, replacing the previous link.
+// The last synced commit is reset, so that the next sync compares every file,
+// and the webhook secret is kept.
+func (this gitSourcesRepository) SaveSource(c context.Context, source models.GitSource) (models.GitSource, error) {
+	_, err := this.db.NewInsert().
+		Model(&source).
+		On("CONFLICT (post_slug) DO UPDATE").
+		Set("url = EXCLUDED.url").
+		Set("branch = EXCLUDED.branch").
+		Set("path_glob = EXCLUDED.path_glob").
+		Set("last_commit = ''").
+		Set("last_synced_at = NULL").
+		Set("webhook_secret = COALESCE(NULLIF(gs.webhook_secret, ''), EXCLUDED.webhook_secret)").
+		Returning("*").
+		Exec(c)
+
+	return source, err
+}
+
+func (this gitSourcesRepository) DeleteSource(c context.Context, slug uuid.UUID) (uuid.UUID, error) {
+	_, err := this.db.NewDelete().Model((*models.GitSource)(nil)).Where("post_slug = ?", slug).Exec(c)
+
+	return slug, err
+}
+
+func (this gitSourcesRepository) SetSynced(c context.Context, slug uuid.UUID, commit string) error {
+	_, err := this.db.NewUpdate().
+		Model((*models.GitSource)(nil)).
+		Set("last_commit = ?", commit).
+		Set("last_synced_at = ?", time.Now()).
+		Where("post_slug = ?", slug).
+		Exec(c)
+
+	return err
+}
+
+// CreateDelivery logs the delivery, it returns false when the delivery was
+// already received.
+func (this gitSourcesRepository) CreateDelivery(c context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, bool, error) {
+	res, err := this.db.NewInsert().
+		Model(&delivery).
+		On("CONFLICT (post_slug, delivery_id) DO NOTHING").
+		Exec(c)
+	if err != nil {
+		return delivery, false, err
+	}
+
+	rows, err := res.RowsAffected()
+
+	return delivery, rows > 0, err
+}
+
+func (this gitSourcesRepository) UpdateDelivery(c context.Context, delivery models.WebhookDelivery) error {
+	_, err := this.db.NewUpdate().
+		Model(&delivery).
+		Column("commit", "status", "message").
+		WherePK().
+		Exec(c)
+
+	return err
+}
+
+func (this gitSourcesRepository) GetDeliveries(c context.Context, slug uuid.UUID, limit int) (deliveries []models.WebhookDelivery, err error) {
+	deliveries = []models.WebhookDelivery{}
+
+	err = this.db.NewSelect().
+		Model(&deliveries).
+		Where("post_slug = ?", slug).
+		Order("created_at DESC").
+		Limit(limit).
+		Scan(c)
+
+	return
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type HistoryRepository interface {
+	GetEntries(c context.Context, userId uuid.UUID, offset int, limit int) ([]models.SearchHistoryEntry, int, error)
+	CreateEntry(c context.Context, entry models.SearchHistoryEntry) (models.SearchHistoryEntry, error)
+	DeleteEntry(c context.Context, userId uuid.UUID, id uuid.UUID) (uuid.UUID, error)
+	DeleteEntriesFor(c context.Context, userId uuid.UUID) (uuid.UUID, error)
+}
+
+type historyRepository struct {
+	db *bun.DB
+}
+
+func NewHistoryRepository(db *bun.DB) HistoryRepository {
+	return historyRepository{db}
+}
+
+func (this historyRepository) GetEntries(c context.Context, userId uuid.UUID, offset int, limit int) (entries []models.SearchHistoryEntry, total int, err error) {
+	entries = []models.SearchHistoryEntry{}
+
+	total, err = this.db.NewSelect().
+		Model(&entries).
+		Relation("Post").
+		Where("sh.user_id = ?", userId).
+		Order("sh.created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		ScanAndCount(c)
+
+	return
+}
+
+func (this historyRepository) CreateEntry(c context.Context, entry models.SearchHistoryEntry) (models.SearchHistoryEntry, error) {
+	_, err := this.db.NewInsert().Model(&entry).Exec(c)
+
+	return entry, err
+}
+
+func (this historyRepository) DeleteEntry(c context.Context, userId uuid.UUID, id uuid.UUID) (uuid.UUID, error) {
+	_, err := this.db.NewDelete().Model((*models.SearchHistoryEntry)(nil)).Where("user_id = ?", userId).Where("id = ?", id).Exec(c)
+
+	return id, err
+}
+
+func (this historyRepository) DeleteEntriesFor(c context.Context, userId uuid.UUID) (uuid.UUID, error) {
+	_, err := this.db.NewDelete().Model((*models.SearchHistoryEntry)(nil)).Where("user_id = ?", userId).Exec(c)
+
+	return userId, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"webapp-go/webapp/blobs"
 	"webapp-go/webapp/models"
 
 	"github.com/google/uuid"
@@ -14,14 +15,17 @@ type PostsRepository interface {
 	CreatePost(c context.Context, post models.Post) (models.Post, error)
 	UpdatePost(c context.Context, slug uuid.UUID, post models.Post) (models.Post, error)
 	DeletePost(c context.Context, slug uuid.UUID) (uuid.UUID, error)
+	GetDocumentVersion(c context.Context, slug uuid.UUID) (int, error)
+	BumpDocumentVersion(c context.Context, slug uuid.UUID) (int, error)
 }
 
 type postsRepository struct {
-	db *bun.DB
+	db    *bun.DB
+	blobs blobs.BlobStore
 }
 
-func NewPostsRepository(db *bun.DB) PostsRepository {
-	return postsRepository{db}
+func NewPostsRepository(db *bun.DB, blobs blobs.BlobStore) PostsRepository {
+	return postsRepository{db, blobs}
 }
 
 func (this postsRepository) GetPost(c context.Context, slug uuid.UUID) (post models.Post, err error) {
@@ -52,8 +56,36 @@ func (this postsRepository) UpdatePost(c context.Context, slug uuid.UUID, post m
 	return post, err
 }
 
+// DeletePost deletes the post, its documents and their blobs.
 func (this postsRepository) DeletePost(c context.Context, slug uuid.UUID) (uuid.UUID, error) {
-	_, err := this.db.NewDelete().Model((*models.Post)(nil)).Where("slug = ?", slug).Exec(c)
+	keys, err := contentKeys(c, this.db, slug, uuid.Nil)
+	if err != nil {
+		return slug, err
+	}
 
-	return slug, err
+	_, err = this.db.NewDelete().Model((*models.Post)(nil)).Where("slug = ?", slug).Exec(c)
+	if err != nil {
+		return slug, err
+	}
+
+	deleteBlobs(c, this.blobs, keys)
+
+	return slug, nil
+}
+
+func (this postsRepository) GetDocumentVersion(c context.Context, slug uuid.UUID) (version int, err error) {
+	err = this.db.NewSelect().Model((*models.Post)(nil)).Column("document_version").Where("slug = ?", slug).Scan(c, &version)
+
+	return
+}
+
+func (this postsRepository) BumpDocumentVersion(c context.Context, slug uuid.UUID) (version int, err error) {
+	_, err = this.db.NewUpdate().
+		Model((*models.Post)(nil)).
+		Set("document_version = document_version + 1").
+		Where("slug = ?", slug).
+		Returning("document_version").
+		Exec(c, &version)
+
+	return
 }
@@ -13,6 +13,7 @@ type UsersRepository interface {
 	GetUserByLogin(c context.Context, githubUsername string) (models.User, error)
 	CreateUser(c context.Context, user models.User) (models.User, error)
 	UpdateUser(c context.Context, id uuid.UUID, user models.User) (models.User, error)
+	SetSearchHistory(c context.Context, id uuid.UUID, enabled bool) (models.User, error)
 }
 
 type usersRepository struct {
@@ -48,3 +49,14 @@ func (this usersRepository) UpdateUser(c context.Context, id uuid.UUID, user mod
 
 	return user, err
 }
+
+func (this usersRepository) SetSearchHistory(c context.Context, id uuid.UUID, enabled bool) (user models.User, err error) {
+	_, err = this.db.NewUpdate().
+		Model(&user).
+		Set("search_history = ?", enabled).
+		Where("id = ?", id).
+		Returning("*").
+		Exec(c)
+
+	return
+}
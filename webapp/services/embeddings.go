@@ -4,72 +4,356 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"time"
+	"webapp-go/webapp/config"
 	"webapp-go/webapp/models"
 	"webapp-go/webapp/repositories"
 
 	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/schema"
 )
 
 type EmbeddingsService interface {
-	GetSearchResult(c context.Context, slug uuid.UUID, query models.SearchQuery) (models.SearchResult, error)
+	GetSearchResult(c context.Context, userId uuid.UUID, slug uuid.UUID, query models.SearchQuery) (models.SearchResult, error)
+	Retrieve(c context.Context, slug uuid.UUID, query models.SearchQuery) ([]models.DocumentScore, []models.Document, error)
+	Answer(c context.Context, question string, scores []models.DocumentScore, documents []models.Document) (string, error)
+	NearDuplicates(c context.Context, slug uuid.UUID, documents []models.Document) ([][]models.DocumentWarning, error)
 	Worker(c context.Context)
 }
 
 type embeddingsService struct {
-	documentsRepo  repositories.DocumentsRepository
-	embeddingsRepo repositories.EmbeddingsRepository
-	llm            *ollama.LLM
-	documentChan   <-chan models.DocumentChanItem
+	cfg             config.Config
+	postsRepo       repositories.PostsRepository
+	documentsRepo   repositories.DocumentsRepository
+	embeddingsRepo  repositories.EmbeddingsRepository
+	searchCacheRepo repositories.SearchCacheRepository
+	answersRepo     repositories.AnswersRepository
+	historyService  HistoryService
+	llm             *ollama.LLM
+	documentChan    <-chan models.DocumentChanItem
 }
 
-func NewEmbeddingsService(documentsRepo repositories.DocumentsRepository, embeddingsRepo repositories.EmbeddingsRepository, llm *ollama.LLM, documentChan <-chan models.DocumentChanItem) EmbeddingsService {
-	return embeddingsService{documentsRepo, embeddingsRepo, llm, documentChan}
+func NewEmbeddingsService(cfg config.Config, postsRepo repositories.PostsRepository, documentsRepo repositories.DocumentsRepository, embeddingsRepo repositories.EmbeddingsRepository, searchCacheRepo repositories.SearchCacheRepository, answersRepo repositories.AnswersRepository, historyService HistoryService, llm *ollama.LLM, documentChan <-chan models.DocumentChanItem) EmbeddingsService {
+	return embeddingsService{cfg, postsRepo, documentsRepo, embeddingsRepo, searchCacheRepo, answersRepo, historyService, llm, documentChan}
 }
 
-func (this embeddingsService) createEmbeddings(c context.Context, slug uuid.UUID, id uuid.UUID) {
-	document, err := this.documentsRepo.GetDocument(c, slug, id)
-	if err != nil {
-		slog.Error("Error getting the document with id", "id", id, "error", err.Error())
-		return
+type pendingChunk struct {
+	documentID uuid.UUID
+	chunk      models.DocumentChunk
+}
+
+// embedBatch creates the embeddings for the chunks of a batch of created or
+// updated documents. Chunks found in the embedding cache are not sent to the
+// model, the rest are embedded with a single request, and the results are
+// written back in one transaction.
+func (this embeddingsService) embedBatch(c context.Context, batch []models.DocumentChanItem) {
+	model := this.cfg.Ollama.Model
+
+	// The same document might have been queued more than once
+	items := map[uuid.UUID]models.DocumentChanItem{}
+	for _, d := range batch {
+		items[d.ID] = d
 	}
 
-	content := document.ParseContent()
+	documentIDs := []uuid.UUID{}
+	documents := map[uuid.UUID]models.Document{}
+	embeddings := []models.DocumentEmbedding{}
+	cacheEntries := []models.EmbeddingCacheEntry{}
 
-	embeddings, err := this.llm.CreateEmbedding(c, []string{content})
-	if err != nil {
-		slog.Error("Error generating embeddings for document with id", "id", id, "error", err.Error())
+	// Chunks with the same content share a single embedding request
+	pending := map[string][]pendingChunk{}
+	pendingHashes := []string{}
+	pendingContents := []string{}
+
+	for id, d := range items {
+		document, err := this.documentsRepo.GetDocument(c, d.PostSlug, id)
+		if err != nil {
+			slog.Error("Error getting the document with id", "id", id, "error", err.Error())
+			continue
+		}
+
+		document.Content, err = this.documentsRepo.GetContent(c, document)
+		if err != nil {
+			slog.Error("Error getting the content of the document with id", "id", id, "error", err.Error())
+			continue
+		}
+
+		// Documents that cannot be parsed keep their previous embeddings
+		parsed, err := document.Parse()
+		if err != nil {
+			slog.Error("Error parsing the document with id", "id", id, "contentType", document.ContentType, "error", err.Error())
+			this.setIndexStatus(c, document, models.INDEX_FAILED, err)
+			continue
+		}
+
+		document.Describe(parsed)
+		documentIDs = append(documentIDs, id)
+		documents[id] = document
+
+		for _, chunk := range models.NewDocumentChunks(parsed, this.cfg.Embeddings.ChunkSize) {
+			hash := models.ContentHash([]byte(chunk.Text))
+
+			entry, err := this.embeddingsRepo.GetCachedEmbedding(c, hash, model)
+			if err == nil {
+				slog.Debug("Using cached embedding", "id", id, "chunk", chunk.Index, "hash", hash, "model", model)
+
+				embeddings = append(embeddings, models.NewDocumentEmbedding(id, chunk, entry.Embeddings))
+				continue
+			}
+
+			if _, ok := pending[hash]; !ok {
+				pendingHashes = append(pendingHashes, hash)
+				pendingContents = append(pendingContents, chunk.Text)
+			}
+			pending[hash] = append(pending[hash], pendingChunk{id, chunk})
+		}
+	}
+
+	if len(pendingContents) > 0 {
+		slog.Info("Generating embeddings", "chunks", len(pendingContents), "model", model)
+
+		vectors, err := this.llm.CreateEmbedding(c, pendingContents)
+		if err != nil {
+			slog.Error("Error generating embeddings for documents", "ids", documentIDs, "error", err.Error())
+
+			// Keep the previous embeddings of the documents that could not be embedded
+			failed := map[uuid.UUID]bool{}
+			for _, chunks := range pending {
+				for _, p := range chunks {
+					failed[p.documentID] = true
+				}
+			}
+
+			for id := range failed {
+				this.setIndexStatus(c, documents[id], models.INDEX_FAILED, err)
+			}
+
+			documentIDs = slices.DeleteFunc(documentIDs, func(id uuid.UUID) bool { return failed[id] })
+			embeddings = slices.DeleteFunc(embeddings, func(e models.DocumentEmbedding) bool { return failed[e.DocumentID] })
+		} else {
+			for i, v := range vectors {
+				for _, p := range pending[pendingHashes[i]] {
+					embeddings = append(embeddings, models.NewDocumentEmbedding(p.documentID, p.chunk, v))
+				}
+				cacheEntries = append(cacheEntries, models.NewEmbeddingCacheEntry(pendingHashes[i], model, v))
+			}
+		}
+	}
+
+	if len(documentIDs) == 0 {
 		return
 	}
 
-	_, err = this.embeddingsRepo.CreateEmbedding(c, models.NewDocumentEmbedding(id, embeddings[0]))
+	err := this.embeddingsRepo.SaveEmbeddings(c, documentIDs, embeddings, cacheEntries)
 	if err != nil {
-		slog.Error("Error saving the embeddings for document with id", "id", id, "error", err.Error())
+		slog.Error("Error saving the embeddings for documents", "ids", documentIDs, "error", err.Error())
+
+		for _, id := range documentIDs {
+			this.setIndexStatus(c, documents[id], models.INDEX_FAILED, err)
+		}
 		return
 	}
+
+	vectors := map[uuid.UUID][][]float32{}
+	for _, e := range embeddings {
+		vectors[e.DocumentID] = append(vectors[e.DocumentID], e.Embeddings)
+	}
+
+	for _, id := range documentIDs {
+		document := documents[id]
+		document.Warnings = this.duplicateWarnings(c, document, vectors[id])
+		this.setIndexStatus(c, document, models.INDEX_DONE, nil)
+	}
 }
 
-func (this embeddingsService) updateEmbeddings(c context.Context, slug uuid.UUID, documentID uuid.UUID) {
-	document, err := this.documentsRepo.GetDocument(c, slug, documentID)
-	if err != nil {
-		slog.Error("Error getting the document with id", "id", documentID, "error", err.Error())
-		return
+// setIndexStatus records the outcome of the indexing of the document, with
+// the error that made it fail.
+func (this embeddingsService) setIndexStatus(c context.Context, document models.Document, status string, cause error) {
+	document.IndexStatus = status
+	document.IndexError = ""
+	if cause != nil {
+		document.IndexError = cause.Error()
+	}
+
+	if err := this.documentsRepo.SetIndexStatus(c, document); err != nil {
+		slog.Error("Error saving the index status of the document with id", "id", document.ID, "status", status, "error", err.Error())
+	}
+}
+
+// nearDuplicatesLimit is the most near duplicates reported for a document.
+const nearDuplicatesLimit = 5
+
+// meanEmbedding returns the mean of the embeddings of the chunks of a
+// document, which is compared to the ones of the other documents.
+func meanEmbedding(vectors [][]float32) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	mean := make([]float32, len(vectors[0]))
+	for _, v := range vectors {
+		for i := range min(len(v), len(mean)) {
+			mean[i] += v[i] / float32(len(vectors))
+		}
 	}
 
-	content := document.ParseContent()
+	return mean
+}
+
+// cosineSimilarity returns the cosine similarity of the two embeddings.
+func cosineSimilarity(a []float32, b []float32) float32 {
+	dot, na, nb := 0.0, 0.0, 0.0
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i] * b[i])
+		na += float64(a[i] * a[i])
+		nb += float64(b[i] * b[i])
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+// NearDuplicates compares documents about to be created to the documents of
+// the post, and to the ones before them in the list, by the mean embedding of
+// their chunks. It returns the near duplicates of each document. The chunks
+// are looked up in the embedding cache first, the missing ones are embedded
+// with a single request and cached, so that the worker does not embed them
+// again when it indexes the documents.
+func (this embeddingsService) NearDuplicates(c context.Context, slug uuid.UUID, documents []models.Document) ([][]models.DocumentWarning, error) {
+	model := this.cfg.Ollama.Model
+
+	vectors := make([][][]float32, len(documents))
 
-	embeddings, err := this.llm.CreateEmbedding(c, []string{content})
+	// Chunks with the same content share a single embedding request
+	pending := map[string][]int{}
+	pendingHashes := []string{}
+	pendingContents := []string{}
+
+	for i, document := range documents {
+		// The documents that cannot be parsed are reported by the worker
+		parsed, err := document.Parse()
+		if err != nil {
+			continue
+		}
+
+		for _, chunk := range models.NewDocumentChunks(parsed, this.cfg.Embeddings.ChunkSize) {
+			hash := models.ContentHash([]byte(chunk.Text))
+
+			entry, err := this.embeddingsRepo.GetCachedEmbedding(c, hash, model)
+			if err == nil {
+				vectors[i] = append(vectors[i], entry.Embeddings)
+				continue
+			}
+
+			if _, ok := pending[hash]; !ok {
+				pendingHashes = append(pendingHashes, hash)
+				pendingContents = append(pendingContents, chunk.Text)
+			}
+			pending[hash] = append(pending[hash], i)
+		}
+	}
+
+	if len(pendingContents) > 0 {
+		slog.Info("Generating embeddings to find near duplicates", "chunks", len(pendingContents), "model", model)
+
+		generated, err := this.llm.CreateEmbedding(c, pendingContents)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, v := range generated {
+			for _, i := range pending[pendingHashes[j]] {
+				vectors[i] = append(vectors[i], v)
+			}
+
+			if _, err := this.embeddingsRepo.SaveCachedEmbedding(c, models.NewEmbeddingCacheEntry(pendingHashes[j], model, v)); err != nil {
+				slog.Warn("Error caching the embedding", "hash", pendingHashes[j], "model", model, "error", err.Error())
+			}
+		}
+	}
+
+	threshold := this.cfg.Embeddings.DuplicateThreshold
+	means := make([][]float32, len(documents))
+	warnings := make([][]models.DocumentWarning, len(documents))
+
+	for i, document := range documents {
+		warnings[i] = []models.DocumentWarning{}
+
+		means[i] = meanEmbedding(vectors[i])
+		if means[i] == nil {
+			continue
+		}
+
+		similar, err := this.embeddingsRepo.GetSimilarDocuments(c, slug, document.ID, means[i], threshold, nearDuplicatesLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range similar {
+			w.Kind = models.WARNING_NEAR_DUPLICATE
+			warnings[i] = append(warnings[i], w)
+		}
+
+		for j := range i {
+			if means[j] == nil {
+				continue
+			}
+
+			if similarity := cosineSimilarity(means[i], means[j]); similarity >= threshold {
+				warnings[i] = append(warnings[i], models.DocumentWarning{Kind: models.WARNING_NEAR_DUPLICATE, DocumentID: documents[j].ID, Filename: documents[j].Filename, Similarity: similarity})
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// duplicateWarnings lists the other documents of the post with the same
+// content as the indexed document, then the ones whose mean embedding is
+// similar to the mean of its chunks. The documents indexed in the same batch
+// are compared too, as their embeddings are saved first.
+func (this embeddingsService) duplicateWarnings(c context.Context, document models.Document, vectors [][]float32) []models.DocumentWarning {
+	warnings := []models.DocumentWarning{}
+
+	others, err := this.documentsRepo.GetDocuments(c, document.PostSlug)
 	if err != nil {
-		slog.Error("Error generating embeddings for document with id", "id", documentID, "error", err.Error())
-		return
+		slog.Warn("Error getting the documents of the post with slug", "slug", document.PostSlug, "error", err.Error())
 	}
 
-	_, err = this.embeddingsRepo.UpdateEmbeddingFor(c, documentID, models.NewDocumentEmbedding(documentID, embeddings[0]))
+	exact := map[uuid.UUID]bool{}
+	for _, d := range others {
+		if d.ID != document.ID && d.ContentHash == document.ContentHash {
+			warnings = append(warnings, models.DocumentWarning{Kind: models.WARNING_DUPLICATE, DocumentID: d.ID, Filename: d.Filename, Similarity: 1})
+			exact[d.ID] = true
+		}
+	}
+
+	if len(vectors) == 0 {
+		return warnings
+	}
+
+	similar, err := this.embeddingsRepo.GetSimilarDocuments(c, document.PostSlug, document.ID, meanEmbedding(vectors), this.cfg.Embeddings.DuplicateThreshold, nearDuplicatesLimit)
 	if err != nil {
-		slog.Error("Error saving the embeddings for document with id", "id", documentID, "error", err.Error())
-		return
+		slog.Warn("Error looking for near duplicates of the document with id", "id", document.ID, "error", err.Error())
+	}
+
+	for _, w := range similar {
+		if !exact[w.DocumentID] {
+			w.Kind = models.WARNING_NEAR_DUPLICATE
+			warnings = append(warnings, w)
+		}
 	}
+
+	return warnings
 }
 
 func (this embeddingsService) deleteEmbeddings(c context.Context, documentID uuid.UUID) {
@@ -80,70 +364,361 @@ func (this embeddingsService) deleteEmbeddings(c context.Context, documentID uui
 	}
 }
 
+// invalidateSearchCache bumps the document version of the post and deletes its
+// cached answers. A search that started before the embeddings changed saves its
+// answer under the version it read, which the bump makes unreachable.
+func (this embeddingsService) invalidateSearchCache(c context.Context, slug uuid.UUID) {
+	if _, err := this.postsRepo.BumpDocumentVersion(c, slug); err != nil {
+		slog.Error("Error bumping the document version for post with slug", "slug", slug, "error", err.Error())
+	}
+
+	_, err := this.searchCacheRepo.DeleteEntriesFor(c, slug)
+	if err != nil {
+		slog.Error("Error invalidating the search cache for post with slug", "slug", slug, "error", err.Error())
+		return
+	}
+}
+
+// indexBatch embeds a batch of documents. A panic, such as one raised by a
+// parser on a malformed file, marks the documents of the batch as failed
+// instead of stopping the worker.
+func (this embeddingsService) indexBatch(c context.Context, batch []models.DocumentChanItem) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		cause := fmt.Errorf("indexing failed: %v", r)
+		slog.Error("Recovered from a panic while indexing documents", "error", cause.Error(), "stack", string(debug.Stack()))
+
+		for _, d := range batch {
+			document, err := this.documentsRepo.GetDocument(c, d.PostSlug, d.ID)
+			if err != nil {
+				slog.Error("Error getting the document with id", "id", d.ID, "error", err.Error())
+				continue
+			}
+
+			this.setIndexStatus(c, document, models.INDEX_FAILED, cause)
+		}
+	}()
+
+	this.embedBatch(c, batch)
+}
+
+// Worker consumes the document channel. Created and updated documents are
+// grouped in batches that are flushed when they reach the maximum batch size
+// or when the oldest item has waited for the maximum batch latency.
 func (this embeddingsService) Worker(c context.Context) {
-	for d := range this.documentChan {
-		switch d.Command {
-		case models.CREATE:
-			this.createEmbeddings(c, d.PostSlug, d.ID)
-		case models.UPDATE:
-			this.updateEmbeddings(c, d.PostSlug, d.ID)
-		case models.DELETE:
-			this.deleteEmbeddings(c, d.ID)
-		default:
-			slog.Error("Unknown command", "command", d.Command)
+	batchSize := max(this.cfg.Embeddings.BatchSize, 1)
+	batchLatency := this.cfg.Embeddings.BatchLatency
+
+	batch := []models.DocumentChanItem{}
+
+	timer := time.NewTimer(batchLatency)
+	timer.Stop()
+
+	// A tick left in the channel after Stop would flush the next batch early,
+	// it is drained without blocking as the select might have received it
+	stopTimer := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+
+	flush := func() {
+		stopTimer()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		this.indexBatch(c, batch)
+
+		// Answers cached while the embeddings were being updated are stale
+		slugs := map[uuid.UUID]bool{}
+		for _, d := range batch {
+			slugs[d.PostSlug] = true
+		}
+		for slug := range slugs {
+			this.invalidateSearchCache(c, slug)
+		}
+
+		batch = []models.DocumentChanItem{}
+	}
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-timer.C:
+			flush()
+		case d, ok := <-this.documentChan:
+			if !ok {
+				flush()
+				return
+			}
+
+			switch d.Command {
+			case models.CREATE, models.UPDATE:
+				if len(batch) == 0 {
+					stopTimer()
+					timer.Reset(batchLatency)
+				}
+
+				batch = append(batch, d)
+				if len(batch) >= batchSize {
+					flush()
+				}
+			case models.DELETE:
+				// Keep the order of the commands for the same document
+				flush()
+
+				this.deleteEmbeddings(c, d.ID)
+				this.invalidateSearchCache(c, d.PostSlug)
+			default:
+				slog.Error("Unknown command", "command", d.Command)
+			}
 		}
 	}
 }
 
-func (this embeddingsService) buildPrompt(question string, documents []models.Document) string {
-	prompt := "You are given a list of documents as well as the file contents. Your task is to provide a useful response based on this knowledge: \n"
+func (this embeddingsService) buildPrompt(question string, scores []models.DocumentScore, documents []models.Document) string {
+	prompt := "You are given a list of passages from documents as well as their titles. Your task is to provide a useful response based on this knowledge, citing the titles of the passages you use: \n"
 
-	contents := []string{}
+	filenames := map[uuid.UUID]string{}
 	for _, d := range documents {
-		contents = append(contents, d.FormatPrompt())
+		filenames[d.ID] = d.Filename
+	}
+
+	contents := []string{}
+	for _, s := range scores {
+		filename, ok := filenames[s.DocumentID]
+		if !ok {
+			continue
+		}
+
+		contents = append(contents, s.FormatPrompt(filename))
 	}
 	context := strings.Join(contents, "\n")
 
 	return fmt.Sprintf("%s\n%s\nQuestion: %s\nAnswer: ", prompt, context, question)
 }
 
-func (this embeddingsService) GetSearchResult(c context.Context, slug uuid.UUID, query models.SearchQuery) (result models.SearchResult, err error) {
-	slog.Info("Searching for ", "query", query.Query)
+func (this embeddingsService) generate(c context.Context, prompt string) (response string, tokens models.SearchExplainTokens, err error) {
+	resp, err := this.llm.GenerateContent(c, []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)})
+	if err != nil {
+		return
+	}
 
-	es, err := this.llm.CreateEmbedding(c, []string{query.Query})
+	if len(resp.Choices) == 0 {
+		err = fmt.Errorf("empty response from model %s", this.cfg.Ollama.Model)
+		return
+	}
+
+	choice := resp.Choices[0]
+	response = choice.Content
+
+	tokens.Prompt, _ = choice.GenerationInfo["PromptTokens"].(int)
+	tokens.Completion, _ = choice.GenerationInfo["CompletionTokens"].(int)
+	tokens.Total, _ = choice.GenerationInfo["TotalTokens"].(int)
+
+	return
+}
+
+func (this embeddingsService) GetSearchResult(c context.Context, userId uuid.UUID, slug uuid.UUID, query models.SearchQuery) (result models.SearchResult, err error) {
+	result, err = this.cachedSearch(c, slug, query)
 	if err != nil {
 		return
 	}
 
-	scores, err := this.embeddingsRepo.GetSimilarEmbeddings(c, slug, es[0], query.Limit)
+	// Persist the answer so that feedback can be given on it
+	answer, err := this.answersRepo.CreateAnswer(c, models.NewSearchAnswer(userId, slug, query, result, this.cfg.Ollama.Model))
+	if err != nil {
+		slog.Error("Error saving the search answer", "query", query.Query, "error", err.Error())
+	} else {
+		result.ID = answer.ID
+	}
+
+	this.historyService.Record(c, userId, slug, query.Query, result.ID)
+
+	return result, nil
+}
+
+func (this embeddingsService) cachedSearch(c context.Context, slug uuid.UUID, query models.SearchQuery) (result models.SearchResult, err error) {
+	// The explain mode needs the timings of a real run, so it skips the cache
+	if !this.cfg.SearchCache.Enabled || query.Explain {
+		return this.search(c, slug, query)
+	}
+
+	version, err := this.postsRepo.GetDocumentVersion(c, slug)
+	if err != nil {
+		return
+	}
+
+	entry, err := this.searchCacheRepo.GetEntry(c, slug, models.NormaliseQuery(query.Query), query.Limit, version)
+	if err == nil {
+		slog.Info("Using cached search result", "query", query.Query, "id", entry.ID)
+
+		return entry.SearchResult(), nil
+	}
+
+	result, err = this.search(c, slug, query)
 	if err != nil {
 		return
 	}
 
+	_, cacheErr := this.searchCacheRepo.SaveEntry(c, models.NewSearchCacheEntry(slug, query, version, result, this.cfg.SearchCache.TTL))
+	if cacheErr != nil {
+		slog.Error("Error saving the search result in cache", "query", query.Query, "error", cacheErr.Error())
+	}
+
+	if cacheErr = this.searchCacheRepo.DeleteExpiredEntries(c); cacheErr != nil {
+		slog.Error("Error deleting expired search cache entries", "error", cacheErr.Error())
+	}
+
+	return
+}
+
+// getScoredDocuments returns the distinct documents of the scored chunks, in
+// the order of their best chunk.
+func (this embeddingsService) getScoredDocuments(c context.Context, slug uuid.UUID, scores []models.DocumentScore) []models.Document {
 	documents := []models.Document{}
+	seen := map[uuid.UUID]bool{}
 	for _, s := range scores {
+		if seen[s.DocumentID] {
+			continue
+		}
+		seen[s.DocumentID] = true
+
 		d, err := this.documentsRepo.GetDocument(c, slug, s.DocumentID)
 		if err != nil {
 			slog.Error("Error getting document with id", "id", s.DocumentID, "error", err.Error())
 			continue
 		}
 
-		slog.Info("Found document", "filename", d.Filename, "score", s.Score)
+		slog.Info("Found document", "filename", d.Filename, "page", s.Page, "score", s.Score)
 
 		documents = append(documents, d)
 	}
 
-	prompt := this.buildPrompt(query.Query, documents)
+	return documents
+}
+
+// retrieveMaxChunks bounds the chunks fetched by Retrieve, as a multiple of
+// the number of documents asked for.
+const retrieveMaxChunks = 32
+
+// keepDocuments keeps the chunks of the first limit distinct documents of the
+// scores, and returns how many distinct documents the scores hold.
+func keepDocuments(scores []models.DocumentScore, limit int) ([]models.DocumentScore, int) {
+	kept := []models.DocumentScore{}
+	ranks := map[uuid.UUID]int{}
+	for _, s := range scores {
+		if _, ok := ranks[s.DocumentID]; !ok {
+			ranks[s.DocumentID] = len(ranks) + 1
+		}
+		if ranks[s.DocumentID] <= limit {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept, len(ranks)
+}
+
+// Retrieve returns the query.Limit documents most similar to the query along
+// with their chunks, without generating a response. Documents often have
+// several of the best chunks, so more chunks are fetched until there are
+// enough distinct documents or the post has no more chunks.
+func (this embeddingsService) Retrieve(c context.Context, slug uuid.UUID, query models.SearchQuery) (scores []models.DocumentScore, documents []models.Document, err error) {
+	es, err := this.llm.CreateEmbedding(c, []string{query.Query})
+	if err != nil {
+		return
+	}
+
+	limit := max(query.Limit, 1)
+	for chunks := limit; ; chunks *= 2 {
+		scores, err = this.embeddingsRepo.GetSimilarEmbeddings(c, slug, es[0], chunks)
+		if err != nil {
+			return
+		}
+
+		_, found := keepDocuments(scores, limit)
+		if found >= limit || len(scores) < chunks || chunks >= limit*retrieveMaxChunks {
+			break
+		}
+	}
+
+	scores, _ = keepDocuments(scores, limit)
+	documents = this.getScoredDocuments(c, slug, scores)
+
+	return
+}
+
+// Answer generates a response to the question based on the given chunks.
+func (this embeddingsService) Answer(c context.Context, question string, scores []models.DocumentScore, documents []models.Document) (string, error) {
+	response, _, err := this.generate(c, this.buildPrompt(question, scores, documents))
+
+	return response, err
+}
+
+func (this embeddingsService) search(c context.Context, slug uuid.UUID, query models.SearchQuery) (result models.SearchResult, err error) {
+	slog.Info("Searching for ", "query", query.Query)
+
+	start := time.Now()
+
+	es, err := this.llm.CreateEmbedding(c, []string{query.Query})
+	if err != nil {
+		return
+	}
+
+	embedded := time.Now()
+
+	scores, err := this.embeddingsRepo.GetSimilarEmbeddings(c, slug, es[0], query.Limit)
+	if err != nil {
+		return
+	}
+
+	documents := this.getScoredDocuments(c, slug, scores)
+
+	found := map[uuid.UUID]models.Document{}
+	for _, d := range documents {
+		found[d.ID] = d
+	}
+
+	retrieved := time.Now()
+
+	prompt := this.buildPrompt(query.Query, scores, documents)
 
 	slog.Info("Using prompt", "prompt", prompt)
 
-	response, err := this.llm.Call(c, prompt)
+	response, tokens, err := this.generate(c, prompt)
 	if err != nil {
 		return
 	}
 
+	generated := time.Now()
+
 	result.Scores = scores
 	result.Response = response
 
+	if query.Explain {
+		result.Explain = &models.SearchExplain{
+			Prompt:   prompt,
+			Model:    this.cfg.Ollama.Model,
+			Passages: models.NewSearchExplainPassages(scores, found),
+			Tokens:   tokens,
+			Timings: models.SearchExplainTimings{
+				Embedding:  embedded.Sub(start).Milliseconds(),
+				Retrieval:  retrieved.Sub(embedded).Milliseconds(),
+				Generation: generated.Sub(retrieved).Milliseconds(),
+				Total:      generated.Sub(start).Milliseconds(),
+			},
+		}
+	}
+
 	return
 }
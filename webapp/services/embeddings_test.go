@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/google/uuid"
+)
+
+type fakeDocuments struct {
+	repositories.DocumentsRepository
+	documents map[uuid.UUID]models.Document
+}
+
+func (this *fakeDocuments) GetDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (models.Document, error) {
+	d, ok := this.documents[id]
+	if !ok {
+		return d, errors.New("no rows in result set")
+	}
+	return d, nil
+}
+
+func (this *fakeDocuments) GetContent(c context.Context, document models.Document) ([]byte, error) {
+	return document.Content, nil
+}
+
+func (this *fakeDocuments) SetIndexStatus(c context.Context, document models.Document) error {
+	this.documents[document.ID] = document
+	return nil
+}
+
+// fakeEmbeddings serves every chunk from the cache, so that the model is
+// never called. It keeps the saved embeddings of the documents and sends the
+// documents of every saved batch.
+type fakeEmbeddings struct {
+	repositories.EmbeddingsRepository
+	documents *fakeDocuments
+	cache     map[string][]float32
+	saved     map[uuid.UUID][][]float32
+	batches   chan []uuid.UUID
+}
+
+func (this *fakeEmbeddings) GetCachedEmbedding(c context.Context, hash string, model string) (models.EmbeddingCacheEntry, error) {
+	v, ok := this.cache[hash]
+	if !ok {
+		return models.EmbeddingCacheEntry{}, errors.New("no rows in result set")
+	}
+	return models.NewEmbeddingCacheEntry(hash, model, v), nil
+}
+
+func (this *fakeEmbeddings) SaveEmbeddings(c context.Context, documentIDs []uuid.UUID, embeddings []models.DocumentEmbedding, cacheEntries []models.EmbeddingCacheEntry) error {
+	if this.saved != nil {
+		for _, id := range documentIDs {
+			delete(this.saved, id)
+		}
+		for _, e := range embeddings {
+			this.saved[e.DocumentID] = append(this.saved[e.DocumentID], e.Embeddings)
+		}
+	}
+	if this.batches != nil {
+		this.batches <- documentIDs
+	}
+	return nil
+}
+
+// fakePosts sends the post of every document version bump.
+type fakePosts struct {
+	repositories.PostsRepository
+	bumped chan uuid.UUID
+}
+
+func (this fakePosts) BumpDocumentVersion(c context.Context, slug uuid.UUID) (int, error) {
+	if this.bumped != nil {
+		this.bumped <- slug
+	}
+	return 2, nil
+}
+
+func (fakePosts) GetDocumentVersion(c context.Context, slug uuid.UUID) (int, error) {
+	return 2, nil
+}
+
+type fakeSearchCache struct {
+	repositories.SearchCacheRepository
+	entries []models.SearchCacheEntry
+}
+
+func (fakeSearchCache) DeleteEntriesFor(c context.Context, slug uuid.UUID) (uuid.UUID, error) {
+	return slug, nil
+}
+
+func (this fakeSearchCache) GetEntry(c context.Context, slug uuid.UUID, query string, limit int, version int) (models.SearchCacheEntry, error) {
+	for _, e := range this.entries {
+		if e.PostSlug == slug && e.Query == query && e.Limit == limit && e.DocumentVersion == version && e.ExpiresAt.After(time.Now()) {
+			return e, nil
+		}
+	}
+	return models.SearchCacheEntry{}, errors.New("no rows in result set")
+}
+
+func TestWorkerBatches(t *testing.T) {
+	slug := uuid.New()
+	latency := 100 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		batchSize int
+		latency   time.Duration
+		documents int
+		close     bool
+		batches   []int
+	}{
+		{name: "full batches", batchSize: 2, latency: time.Hour, documents: 4, batches: []int{2, 2}},
+		{name: "flushed by the timer", batchSize: 2, latency: latency, documents: 3, batches: []int{2, 1}},
+		{name: "flushed on close", batchSize: 16, latency: time.Hour, documents: 3, close: true, batches: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{}
+			cfg.Embeddings.BatchSize = tt.batchSize
+			cfg.Embeddings.BatchLatency = tt.latency
+
+			documents := &fakeDocuments{documents: map[uuid.UUID]models.Document{}}
+			embeddings := &fakeEmbeddings{cache: map[string][]float32{}, batches: make(chan []uuid.UUID, tt.documents)}
+			documentChan := make(chan models.DocumentChanItem, tt.documents)
+			posts := fakePosts{bumped: make(chan uuid.UUID, tt.documents)}
+			service := embeddingsService{cfg: cfg, postsRepo: posts, documentsRepo: documents, embeddingsRepo: embeddings, searchCacheRepo: fakeSearchCache{}, documentChan: documentChan}
+
+			ids := []uuid.UUID{}
+			for i := 0; i < tt.documents; i++ {
+				content := []byte{byte('a' + i)}
+				document := models.Document{ID: uuid.New(), PostSlug: slug, ContentType: "text/plain", Content: content}
+				documents.documents[document.ID] = document
+				embeddings.cache[models.ContentHash(content)] = []float32{float32(i)}
+				ids = append(ids, document.ID)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go service.Worker(ctx)
+
+			start := time.Now()
+			for _, id := range ids {
+				documentChan <- models.NewDocumentChanItem(models.CREATE, slug, id)
+			}
+			if tt.close {
+				close(documentChan)
+			}
+
+			for i, size := range tt.batches {
+				select {
+				case batch := <-embeddings.batches:
+					if len(batch) != size {
+						t.Errorf("Worker() batch %d = %d documents, want %d", i, len(batch), size)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Worker() saved %d batches, want %d", i, len(tt.batches))
+				}
+
+				// Answers of searches running during the batch are saved under the old version
+				select {
+				case bumped := <-posts.bumped:
+					if bumped != slug {
+						t.Errorf("Worker() bumped the document version of %s, want %s", bumped, slug)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Worker() did not bump the document version after batch %d", i)
+				}
+			}
+
+			// Only the timer flushes a batch that is not full
+			if elapsed := time.Since(start); tt.latency < time.Hour && elapsed < tt.latency {
+				t.Errorf("Worker() flushed a partial batch after %v, want at least %v", elapsed, tt.latency)
+			}
+
+			select {
+			case batch := <-embeddings.batches:
+				t.Errorf("Worker() saved an extra batch of %d documents", len(batch))
+			case <-time.After(2 * latency):
+			}
+		})
+	}
+}
+
+func TestKeepDocuments(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	scores := []models.DocumentScore{
+		{DocumentID: a, ChunkIndex: 0},
+		{DocumentID: a, ChunkIndex: 1},
+		{DocumentID: b, ChunkIndex: 0},
+		{DocumentID: c, ChunkIndex: 0},
+		{DocumentID: a, ChunkIndex: 2},
+		{DocumentID: b, ChunkIndex: 1},
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		chunks int
+		found  int
+	}{
+		{name: "one document", limit: 1, chunks: 3, found: 3},
+		{name: "later chunks of kept documents", limit: 2, chunks: 5, found: 3},
+		{name: "every document", limit: 3, chunks: 6, found: 3},
+		{name: "fewer documents than the limit", limit: 5, chunks: 6, found: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, found := keepDocuments(scores, tt.limit)
+			if len(kept) != tt.chunks || found != tt.found {
+				t.Fatalf("keepDocuments(%d) = %d chunks, %d documents, want %d chunks, %d documents", tt.limit, len(kept), found, tt.chunks, tt.found)
+			}
+
+			for _, s := range kept {
+				if tt.limit < 3 && s.DocumentID == c {
+					t.Errorf("keepDocuments(%d) kept a chunk of the third document", tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMeanEmbedding(t *testing.T) {
+	if got := meanEmbedding(nil); got != nil {
+		t.Errorf("meanEmbedding(nil) = %v, want nil", got)
+	}
+
+	got := meanEmbedding([][]float32{{1, 0, 2}, {3, 4, 0}})
+	want := []float32{2, 2, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("meanEmbedding() = %v, want %v", got, want)
+		}
+	}
+}
+
+// embedWords embeds a text as the counts of a few words.
+func embedWords(text string) []float32 {
+	v := []float32{}
+	for _, word := range []string{"recursion", "trees", "graphs", "see"} {
+		v = append(v, float32(strings.Count(strings.ToLower(text), word)))
+	}
+	return v
+}
+
+// cacheChunks puts the embeddings of the chunks of the document in the cache.
+func (this *fakeEmbeddings) cacheChunks(t *testing.T, document models.Document, size int) {
+	parsed, err := document.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range models.NewDocumentChunks(parsed, size) {
+		this.cache[models.ContentHash([]byte(chunk.Text))] = embedWords(chunk.Text)
+	}
+}
+
+func (this *fakeEmbeddings) GetSimilarDocuments(c context.Context, slug uuid.UUID, id uuid.UUID, embedding []float32, threshold float32, limit int) ([]models.DocumentWarning, error) {
+	warnings := []models.DocumentWarning{}
+	for other, vectors := range this.saved {
+		if similarity := cosineSimilarity(embedding, meanEmbedding(vectors)); other != id && similarity >= threshold {
+			warnings = append(warnings, models.DocumentWarning{DocumentID: other, Filename: this.documents.documents[other].Filename, Similarity: similarity})
+		}
+	}
+	return warnings, nil
+}
+
+func TestEmbedBatchDuplicates(t *testing.T) {
+	slug := uuid.New()
+	cfg := config.Config{}
+	cfg.Embeddings.ChunkSize = 2000
+	cfg.Embeddings.DuplicateThreshold = 0.95
+
+	documents := &fakeDocuments{documents: map[uuid.UUID]models.Document{}}
+	embeddings := &fakeEmbeddings{documents: documents, cache: map[string][]float32{}, saved: map[uuid.UUID][][]float32{}}
+	service := embeddingsService{cfg: cfg, documentsRepo: documents, embeddingsRepo: embeddings}
+
+	add := func(filename string, content string) uuid.UUID {
+		document := models.Document{ID: uuid.New(), PostSlug: slug, Filename: filename, ContentType: "text/plain", Content: []byte(content), ContentHash: models.ContentHash([]byte(content))}
+		documents.documents[document.ID] = document
+		embeddings.cacheChunks(t, document, cfg.Embeddings.ChunkSize)
+		return document.ID
+	}
+
+	recursion := add("recursion.txt", "Recursion, see recursion.")
+	copied := add("copy.txt", "Recursion, see recursion.")
+	reworded := add("reworded.txt", "RECURSION: see Recursion!")
+	trees := add("trees.txt", "Trees and graphs.")
+
+	batch := []models.DocumentChanItem{}
+	for _, id := range []uuid.UUID{recursion, copied, reworded, trees} {
+		batch = append(batch, models.NewDocumentChanItem(models.CREATE, slug, id))
+	}
+	service.embedBatch(context.Background(), batch)
+
+	tests := []struct {
+		id       uuid.UUID
+		expected map[uuid.UUID]string
+	}{
+		{id: recursion, expected: map[uuid.UUID]string{copied: models.WARNING_DUPLICATE, reworded: models.WARNING_NEAR_DUPLICATE}},
+		{id: copied, expected: map[uuid.UUID]string{recursion: models.WARNING_DUPLICATE, reworded: models.WARNING_NEAR_DUPLICATE}},
+		{id: reworded, expected: map[uuid.UUID]string{recursion: models.WARNING_NEAR_DUPLICATE, copied: models.WARNING_NEAR_DUPLICATE}},
+		{id: trees, expected: map[uuid.UUID]string{}},
+	}
+
+	for _, tt := range tests {
+		document := documents.documents[tt.id]
+		if document.IndexStatus != models.INDEX_DONE {
+			t.Errorf("embedBatch() status of %s = %q, want %q", document.Filename, document.IndexStatus, models.INDEX_DONE)
+		}
+
+		got := map[uuid.UUID]string{}
+		for _, w := range document.Warnings {
+			got[w.DocumentID] = w.Kind
+		}
+		if len(got) != len(tt.expected) || len(document.Warnings) != len(tt.expected) {
+			t.Errorf("embedBatch() warnings of %s = %+v, want %v", document.Filename, document.Warnings, tt.expected)
+			continue
+		}
+		for id, kind := range tt.expected {
+			if got[id] != kind {
+				t.Errorf("embedBatch() warnings of %s = %+v, want %v", document.Filename, document.Warnings, tt.expected)
+			}
+		}
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	slug := uuid.New()
+	cfg := config.Config{}
+	cfg.Embeddings.ChunkSize = 2000
+	cfg.Embeddings.DuplicateThreshold = 0.95
+
+	documents := &fakeDocuments{documents: map[uuid.UUID]models.Document{}}
+	embeddings := &fakeEmbeddings{documents: documents, cache: map[string][]float32{}, saved: map[uuid.UUID][][]float32{}}
+	service := embeddingsService{cfg: cfg, documentsRepo: documents, embeddingsRepo: embeddings}
+
+	newDocument := func(filename string, content string) models.Document {
+		document := models.Document{ID: uuid.New(), PostSlug: slug, Filename: filename, ContentType: "text/plain", Content: []byte(content)}
+		embeddings.cacheChunks(t, document, cfg.Embeddings.ChunkSize)
+		return document
+	}
+
+	// The indexed document of the post
+	recursion := newDocument("recursion.txt", "Recursion, see recursion.")
+	documents.documents[recursion.ID] = recursion
+	embeddings.saved[recursion.ID] = [][]float32{embedWords("Recursion, see recursion.")}
+
+	uploaded := []models.Document{
+		newDocument("reworded.txt", "RECURSION: see Recursion!"),
+		newDocument("trees.txt", "Trees and graphs."),
+		newDocument("graphs.txt", "Graphs and trees."),
+	}
+
+	warnings, err := service.NearDuplicates(context.Background(), slug, uploaded)
+	if err != nil {
+		t.Fatalf("NearDuplicates() error = %v", err)
+	}
+
+	expected := [][]uuid.UUID{{recursion.ID}, {}, {uploaded[1].ID}}
+	if len(warnings) != len(expected) {
+		t.Fatalf("NearDuplicates() = %d warnings, want %d", len(warnings), len(expected))
+	}
+	for i, want := range expected {
+		if len(warnings[i]) != len(want) {
+			t.Errorf("NearDuplicates() warnings of %s = %+v, want %v", uploaded[i].Filename, warnings[i], want)
+			continue
+		}
+		for j, w := range warnings[i] {
+			if w.DocumentID != want[j] || w.Kind != models.WARNING_NEAR_DUPLICATE || w.Similarity < cfg.Embeddings.DuplicateThreshold {
+				t.Errorf("NearDuplicates() warnings of %s = %+v, want %v", uploaded[i].Filename, warnings[i], want)
+			}
+		}
+	}
+}
+
+func TestCachedSearch(t *testing.T) {
+	slug := uuid.New()
+	cfg := config.Config{}
+	cfg.SearchCache.Enabled = true
+
+	result := models.SearchResult{Response: "A function that calls itself."}
+	cache := fakeSearchCache{entries: []models.SearchCacheEntry{
+		models.NewSearchCacheEntry(slug, models.SearchQuery{Query: "What is recursion?", Limit: 5}, 2, result, time.Hour),
+	}}
+	service := embeddingsService{cfg: cfg, postsRepo: fakePosts{}, searchCacheRepo: cache}
+
+	// The model is nil, so that a cache miss would panic
+	for _, query := range []string{"What is recursion?", "what is   RECURSION", "What is recursion ?!"} {
+		got, err := service.cachedSearch(context.Background(), slug, models.SearchQuery{Query: query, Limit: 5})
+		if err != nil || got.Response != result.Response {
+			t.Errorf("cachedSearch(%q) = %+v, %v, want the cached answer", query, got, err)
+		}
+	}
+}
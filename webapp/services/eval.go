@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/models"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+type EvalService interface {
+	Run(c context.Context, slug uuid.UUID, dataset models.EvalDataset, k int, judge bool) (models.EvalReport, error)
+}
+
+type evalService struct {
+	cfg               config.Config
+	embeddingsService EmbeddingsService
+	llm               *ollama.LLM
+}
+
+func NewEvalService(cfg config.Config, embeddingsService EmbeddingsService, llm *ollama.LLM) EvalService {
+	return evalService{cfg, embeddingsService, llm}
+}
+
+// Run retrieves the top k documents for every question of the dataset and
+// computes recall@k, MRR and nDCG@k. When judge is set, the answers are also
+// generated and scored against the reference answers by the LLM.
+func (this evalService) Run(c context.Context, slug uuid.UUID, dataset models.EvalDataset, k int, judge bool) (report models.EvalReport, err error) {
+	report.PostSlug = slug
+	report.Model = this.cfg.Ollama.Model
+	report.K = k
+	report.Questions = []models.EvalQuestionResult{}
+
+	answerScores := []float64{}
+
+	for _, q := range dataset.Questions {
+		scores, documents, err := this.embeddingsService.Retrieve(c, slug, models.SearchQuery{Query: q.Question, Limit: k})
+		if err != nil {
+			return report, err
+		}
+
+		retrieved := []string{}
+		for _, d := range documents {
+			retrieved = append(retrieved, d.Filename)
+		}
+
+		result := models.EvalQuestionResult{
+			Question:       q.Question,
+			Expected:       q.Documents,
+			Retrieved:      retrieved,
+			Recall:         recallAtK(q.Documents, retrieved),
+			ReciprocalRank: reciprocalRank(q.Documents, retrieved),
+			NDCG:           ndcgAtK(q.Documents, retrieved, k),
+		}
+
+		if judge && q.Answer != "" {
+			answer, err := this.embeddingsService.Answer(c, q.Question, scores, documents)
+			if err != nil {
+				return report, err
+			}
+
+			score, err := this.judgeAnswer(c, q.Question, q.Answer, answer)
+			if err != nil {
+				slog.Error("Error scoring the answer", "question", q.Question, "error", err.Error())
+			} else {
+				result.AnswerScore = &score
+				answerScores = append(answerScores, score)
+			}
+
+			result.Answer = answer
+		}
+
+		report.Recall += result.Recall
+		report.MRR += result.ReciprocalRank
+		report.NDCG += result.NDCG
+		report.Questions = append(report.Questions, result)
+	}
+
+	if n := float64(len(report.Questions)); n > 0 {
+		report.Recall /= n
+		report.MRR /= n
+		report.NDCG /= n
+	}
+
+	if len(answerScores) > 0 {
+		mean := 0.0
+		for _, s := range answerScores {
+			mean += s
+		}
+		mean /= float64(len(answerScores))
+
+		report.AnswerScore = &mean
+	}
+
+	return
+}
+
+var judgeScoreRegexp = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// judgeAnswer asks the LLM to grade the answer against the reference answer
+// and returns the grade normalised to [0, 1].
+func (this evalService) judgeAnswer(c context.Context, question string, reference string, answer string) (float64, error) {
+	prompt := fmt.Sprintf("You are grading the answer to a question against a reference answer. "+
+		"Reply only with a number from 0 (completely wrong) to 10 (as good as the reference).\n\n"+
+		"Question: %s\nReference answer: %s\nAnswer: %s\nGrade: ", question, reference, answer)
+
+	response, err := this.llm.Call(c, prompt)
+	if err != nil {
+		return 0, err
+	}
+
+	match := judgeScoreRegexp.FindString(response)
+	if match == "" {
+		return 0, fmt.Errorf("could not parse the grade from %q", response)
+	}
+
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Min(score, 10) / 10, nil
+}
+
+func isRelevant(expected []string, filename string) bool {
+	for _, e := range expected {
+		if e == filename {
+			return true
+		}
+	}
+
+	return false
+}
+
+// distinct returns the filenames without the repeated ones, in order.
+func distinct(filenames []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, f := range filenames {
+		if !seen[f] {
+			seen[f] = true
+			unique = append(unique, f)
+		}
+	}
+
+	return unique
+}
+
+// recallAtK returns the share of the expected documents that were retrieved.
+// A document retrieved more than once only counts once.
+func recallAtK(expected []string, retrieved []string) float64 {
+	expected = distinct(expected)
+	if len(expected) == 0 {
+		return 0
+	}
+
+	hits := 0
+	for _, r := range distinct(retrieved) {
+		if isRelevant(expected, r) {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(len(expected))
+}
+
+func reciprocalRank(expected []string, retrieved []string) float64 {
+	for i, r := range retrieved {
+		if isRelevant(expected, r) {
+			return 1 / float64(i+1)
+		}
+	}
+
+	return 0
+}
+
+// ndcgAtK returns the normalised discounted cumulative gain of the first k
+// retrieved documents, with a binary relevance. Only the first occurrence of a
+// document gains.
+func ndcgAtK(expected []string, retrieved []string, k int) float64 {
+	expected = distinct(expected)
+
+	dcg := 0.0
+	gained := map[string]bool{}
+	for i, r := range retrieved {
+		if i >= k {
+			break
+		}
+		if isRelevant(expected, r) && !gained[r] {
+			gained[r] = true
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+
+	idcg := 0.0
+	for i := 0; i < min(len(expected), k); i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	if idcg == 0 {
+		return 0
+	}
+
+	return dcg / idcg
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEvalMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  []string
+		retrieved []string
+		k         int
+		recall    float64
+		rank      float64
+		ndcg      float64
+	}{
+		{name: "nothing expected", expected: nil, retrieved: []string{"a.md"}, k: 3},
+		{name: "nothing retrieved", expected: []string{"a.md"}, retrieved: nil, k: 3},
+		{name: "miss", expected: []string{"a.md"}, retrieved: []string{"b.md", "c.md"}, k: 3},
+		{name: "first", expected: []string{"a.md"}, retrieved: []string{"a.md", "b.md", "c.md"}, k: 3, recall: 1, rank: 1, ndcg: 1},
+		{name: "second", expected: []string{"a.md"}, retrieved: []string{"b.md", "a.md", "c.md"}, k: 3, recall: 1, rank: 0.5, ndcg: 1 / math.Log2(3)},
+		{name: "one of two", expected: []string{"a.md", "d.md"}, retrieved: []string{"b.md", "c.md", "a.md"}, k: 3, recall: 0.5, rank: 1.0 / 3, ndcg: 0.5 / (1 + 1/math.Log2(3))},
+		{name: "both in reverse", expected: []string{"a.md", "b.md"}, retrieved: []string{"b.md", "a.md"}, k: 2, recall: 1, rank: 1, ndcg: 1},
+		{name: "more expected than k", expected: []string{"a.md", "b.md", "c.md"}, retrieved: []string{"a.md"}, k: 1, recall: 1.0 / 3, rank: 1, ndcg: 1},
+		{name: "retrieved twice", expected: []string{"a.md"}, retrieved: []string{"a.md", "a.md", "b.md"}, k: 3, recall: 1, rank: 1, ndcg: 1},
+		{name: "expected twice", expected: []string{"a.md", "a.md"}, retrieved: []string{"b.md", "a.md"}, k: 2, recall: 1, rank: 0.5, ndcg: 1 / math.Log2(3)},
+	}
+
+	equal := func(a float64, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recallAtK(tt.expected, tt.retrieved); !equal(got, tt.recall) {
+				t.Errorf("recallAtK() = %v, want %v", got, tt.recall)
+			}
+			if got := reciprocalRank(tt.expected, tt.retrieved); !equal(got, tt.rank) {
+				t.Errorf("reciprocalRank() = %v, want %v", got, tt.rank)
+			}
+			if got := ndcgAtK(tt.expected, tt.retrieved, tt.k); !equal(got, tt.ndcg) {
+				t.Errorf("ndcgAtK() = %v, want %v", got, tt.ndcg)
+			}
+		})
+	}
+}
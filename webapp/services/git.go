@@ -0,0 +1,492 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/parsers"
+	"webapp-go/webapp/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrGitForbidden = errors.New("repository not allowed")
+	ErrGitFailed    = errors.New("git command failed")
+)
+
+type GitService interface {
+	CheckSource(c context.Context, source models.GitSource) error
+	Sync(c context.Context, slug uuid.UUID) (models.GitSyncResult, error)
+	HandlePush(c context.Context, slug uuid.UUID, push models.GitPushPayload) (models.GitSyncResult, bool, error)
+	Worker(c context.Context)
+}
+
+type gitService struct {
+	cfg             config.Config
+	gitSourcesRepo  repositories.GitSourcesRepository
+	documentsRepo   repositories.DocumentsRepository
+	postsRepo       repositories.PostsRepository
+	searchCacheRepo repositories.SearchCacheRepository
+	documentChan    chan<- models.DocumentChanItem
+	pushChan        <-chan models.GitPushChanItem
+	locks           *sync.Map
+}
+
+func NewGitService(cfg config.Config, gitSourcesRepo repositories.GitSourcesRepository, documentsRepo repositories.DocumentsRepository, postsRepo repositories.PostsRepository, searchCacheRepo repositories.SearchCacheRepository, documentChan chan<- models.DocumentChanItem, pushChan <-chan models.GitPushChanItem) GitService {
+	return gitService{cfg, gitSourcesRepo, documentsRepo, postsRepo, searchCacheRepo, documentChan, pushChan, &sync.Map{}}
+}
+
+var (
+	gitRemoteUrl = regexp.MustCompile(`^(https?|ssh|git)://`)
+	gitScpUrl    = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+	gitBranch    = regexp.MustCompile(`^[\w][\w./-]*$`)
+)
+
+// lookupIPAddr resolves the hosts of the repositories.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// gitHost returns the host of a remote repository, or an empty string for a
+// path on the server.
+func gitHost(rawUrl string) (string, error) {
+	if gitRemoteUrl.MatchString(rawUrl) {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			return "", err
+		}
+
+		return u.Hostname(), nil
+	}
+
+	if scp := gitScpUrl.FindString(rawUrl); scp != "" {
+		_, host, _ := strings.Cut(strings.TrimSuffix(scp, ":"), "@")
+
+		return host, nil
+	}
+
+	return "", nil
+}
+
+// checkHost refuses the hosts that resolve to a private address, the same
+// way as the imported pages.
+func checkHost(c context.Context, host string) error {
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := lookupIPAddr(c, host)
+		if err != nil {
+			return fmt.Errorf("%w: cannot resolve %s", ErrGitForbidden, host)
+		}
+
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if isPrivateAddress(ip) {
+			return fmt.Errorf("%w: %s is a private address", ErrGitForbidden, host)
+		}
+	}
+
+	return nil
+}
+
+// CheckSource refuses the repositories that could be used to read the files
+// of the server or to reach the internal network, unless local repositories
+// are allowed. The host is resolved again on every sync, yet git resolves it
+// on its own, so a name that changes its address in between is not caught.
+func (this gitService) CheckSource(c context.Context, source models.GitSource) error {
+	if strings.HasPrefix(source.Url, "-") {
+		return fmt.Errorf("%w: %s", ErrGitForbidden, source.Url)
+	}
+
+	if !this.cfg.Git.AllowLocal {
+		host, err := gitHost(source.Url)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrGitForbidden, err.Error())
+		}
+		if host == "" {
+			return fmt.Errorf("%w: local repositories are disabled", ErrGitForbidden)
+		}
+
+		if err := checkHost(c, host); err != nil {
+			return err
+		}
+	}
+
+	if !gitBranch.MatchString(source.Branch) || strings.Contains(source.Branch, "..") {
+		return fmt.Errorf("%w: invalid branch %s", ErrGitForbidden, source.Branch)
+	}
+
+	return nil
+}
+
+func (this gitService) git(c context.Context, dir string, args ...string) ([]byte, error) {
+	c, cancel := context.WithTimeout(c, this.cfg.Git.Timeout)
+	defer cancel()
+
+	protocols := "https:http:ssh:git"
+	if this.cfg.Git.AllowLocal {
+		protocols += ":file"
+	}
+
+	// Redirects are not followed, as they could lead to an internal address
+	cmd := exec.CommandContext(c, "git", append([]string{"-C", dir, "-c", "http.followRedirects=false"}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL="+protocols)
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	// The output of git is only logged, as it tells about the hosts it reached
+	out, err := cmd.Output()
+	if err != nil {
+		slog.Warn("Git command failed", "command", args[0], "error", err.Error(), "stderr", strings.TrimSpace(stderr.String()))
+		return nil, fmt.Errorf("%w: git %s: %s", ErrGitFailed, args[0], err.Error())
+	}
+
+	return out, nil
+}
+
+// fetch updates the bare copy of the branch kept for the post and returns
+// the commit the branch points to.
+func (this gitService) fetch(c context.Context, source models.GitSource) (dir string, commit string, err error) {
+	dir = filepath.Join(this.cfg.Git.CacheDir, source.PostSlug.String()+".git")
+
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, 0o700); err != nil {
+			return
+		}
+		if _, err = this.git(c, dir, "init", "--bare", "--quiet"); err != nil {
+			return
+		}
+	}
+
+	ref := "refs/heads/" + source.Branch
+	_, err = this.git(c, dir, "fetch", "--quiet", "--no-tags", "--depth=1", "--force", "--", source.Url, "+"+ref+":"+ref)
+	if err != nil {
+		return
+	}
+
+	out, err := this.git(c, dir, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return
+	}
+
+	return dir, strings.TrimSpace(string(out)), nil
+}
+
+type gitBlob struct {
+	path string
+	hash string
+	size int64
+}
+
+// tree lists the regular files of the commit.
+func (this gitService) tree(c context.Context, dir string, commit string) ([]gitBlob, error) {
+	out, err := this.git(c, dir, "ls-tree", "-r", "-z", "--long", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := []gitBlob{}
+	for _, line := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> SP <size> TAB <path>
+		info, p, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(info)
+		if len(fields) != 4 || fields[1] != "blob" || (fields[0] != "100644" && fields[0] != "100755") {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(fields[3], 10, 64)
+		blobs = append(blobs, gitBlob{path: p, hash: fields[2], size: size})
+	}
+
+	return blobs, nil
+}
+
+// globRegexp translates a path glob, where "*" matches within a directory and
+// "**" across directories, to a regular expression. An empty glob matches
+// every path.
+func globRegexp(glob string) *regexp.Regexp {
+	if glob == "" {
+		return regexp.MustCompile(`.*`)
+	}
+
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
+
+func (this gitService) allowedContentType(contentType string) bool {
+	allowed := this.cfg.Uploads.AllowedContentTypes
+	if len(allowed) > 0 && !parsers.MatchContentType(contentType, allowed) {
+		return false
+	}
+
+	return parsers.Supported("", contentType)
+}
+
+// document returns the document for the content of a blob, or false when
+// the blob cannot be indexed.
+func (this gitService) document(c context.Context, dir string, slug uuid.UUID, blob gitBlob) (models.Document, bool, error) {
+	if blob.size > this.cfg.Git.MaxFileSize || len(blob.path) > models.DocumentFilenameMaxLength {
+		return models.Document{}, false, nil
+	}
+
+	content, err := this.git(c, dir, "cat-file", "blob", blob.hash)
+	if err != nil {
+		return models.Document{}, false, err
+	}
+
+	contentType := parsers.DetectContentType(blob.path, content)
+	if !this.allowedContentType(contentType) {
+		return models.Document{}, false, nil
+	}
+
+	document := models.NewDocument(models.DocumentDTO{Filename: blob.path, ContentType: contentType, Content: content, PostSlug: slug})
+
+	parsed, err := document.Parse()
+	if err != nil {
+		slog.Warn("Could not parse file", "filename", blob.path, "error", err.Error())
+	}
+
+	document.Describe(parsed)
+	document.Metadata[models.DOCUMENT_SOURCE_KEY] = models.DOCUMENT_SOURCE_GIT
+	document.Metadata[models.DOCUMENT_GIT_BLOB] = blob.hash
+
+	return document, true, nil
+}
+
+// Sync makes the documents of the post match the files of the repository.
+// Only the files whose blob changed are updated and embedded again, and only
+// the documents that came from the repository are deleted.
+func (this gitService) Sync(c context.Context, slug uuid.UUID) (models.GitSyncResult, error) {
+	source, err := this.gitSourcesRepo.GetSource(c, slug)
+	if err != nil {
+		return models.GitSyncResult{}, err
+	}
+
+	return this.sync(c, source, nil)
+}
+
+// HandlePush syncs the files changed by a push to the branch of the post. It
+// returns false when the push is for another branch. The whole tree is
+// compared when the payload does not list the changed files.
+func (this gitService) HandlePush(c context.Context, slug uuid.UUID, push models.GitPushPayload) (models.GitSyncResult, bool, error) {
+	source, err := this.gitSourcesRepo.GetSource(c, slug)
+	if err != nil {
+		return models.GitSyncResult{}, false, err
+	}
+
+	if push.Ref != "refs/heads/"+source.Branch {
+		return models.GitSyncResult{}, false, nil
+	}
+
+	var only map[string]bool
+	if paths := push.Paths(); len(paths) > 0 {
+		only = map[string]bool{}
+		for _, p := range paths {
+			only[p] = true
+		}
+	}
+
+	result, err := this.sync(c, source, only)
+
+	return result, true, err
+}
+
+// Worker consumes the push deliveries queued by the webhook, one at a time,
+// and records their outcome. Each sync is bounded by the webhook timeout.
+func (this gitService) Worker(c context.Context) {
+	for {
+		select {
+		case <-c.Done():
+			return
+		case item, ok := <-this.pushChan:
+			if !ok {
+				return
+			}
+
+			this.handleDelivery(c, item.Delivery, item.Push)
+		}
+	}
+}
+
+// handleDelivery syncs the post for a push delivery and records the outcome.
+func (this gitService) handleDelivery(c context.Context, delivery models.WebhookDelivery, push models.GitPushPayload) {
+	ctx, cancel := context.WithTimeout(c, this.cfg.Git.WebhookTimeout)
+	defer cancel()
+
+	delivery.Commit = push.After
+
+	result, handled, err := this.HandlePush(ctx, delivery.PostSlug, push)
+	switch {
+	case err != nil:
+		delivery.Status = models.DELIVERY_FAILED
+		delivery.Message = err.Error()
+		slog.Error("Error syncing post for webhook delivery", "slug", delivery.PostSlug, "delivery", delivery.DeliveryID, "error", err.Error())
+	case !handled:
+		delivery.Status = models.DELIVERY_IGNORED
+		delivery.Message = "Push to another branch: " + push.Ref
+	default:
+		delivery.Status = models.DELIVERY_PROCESSED
+		delivery.Commit = result.Commit
+		if message, err := json.Marshal(result); err == nil {
+			delivery.Message = string(message)
+		}
+	}
+
+	// The outcome is recorded even when the sync ran out of time
+	if err := this.gitSourcesRepo.UpdateDelivery(c, delivery); err != nil {
+		slog.Error("Error updating webhook delivery", "delivery", delivery.DeliveryID, "error", err.Error())
+	}
+
+	slog.Info("Handled webhook delivery", "slug", delivery.PostSlug, "delivery", delivery.DeliveryID, "status", delivery.Status)
+}
+
+// sync makes the documents of the post match the files of the repository,
+// only looking at the given paths when there are any.
+func (this gitService) sync(c context.Context, source models.GitSource, only map[string]bool) (result models.GitSyncResult, err error) {
+	slug := source.PostSlug
+
+	lock, _ := this.locks.LoadOrStore(slug, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if err = this.CheckSource(c, source); err != nil {
+		return
+	}
+
+	dir, commit, err := this.fetch(c, source)
+	if err != nil {
+		return
+	}
+
+	result = models.GitSyncResult{Commit: commit, Created: []string{}, Updated: []string{}, Deleted: []string{}, Skipped: []string{}}
+
+	blobs, err := this.tree(c, dir, commit)
+	if err != nil {
+		return
+	}
+
+	documents, err := this.documentsRepo.GetDocuments(c, slug)
+	if err != nil {
+		return
+	}
+
+	existing := map[string]models.Document{}
+	for _, d := range documents {
+		existing[d.Filename] = d
+	}
+
+	glob := globRegexp(source.PathGlob)
+	seen := map[string]bool{}
+	for _, blob := range blobs {
+		if !glob.MatchString(blob.path) || (only != nil && !only[blob.path]) {
+			continue
+		}
+
+		d, ok := existing[blob.path]
+		if ok && d.Metadata[models.DOCUMENT_SOURCE_KEY] == models.DOCUMENT_SOURCE_GIT && d.Metadata[models.DOCUMENT_GIT_BLOB] == blob.hash {
+			seen[blob.path] = true
+			result.Unchanged++
+			continue
+		}
+
+		document, indexable, err := this.document(c, dir, slug, blob)
+		if err != nil {
+			return result, err
+		}
+		if !indexable {
+			result.Skipped = append(result.Skipped, blob.path)
+			continue
+		}
+		seen[blob.path] = true
+
+		if ok {
+			document, err = this.documentsRepo.UpdateDocument(c, slug, d.ID, document, uuid.Nil)
+			if err != nil {
+				return result, err
+			}
+
+			this.documentChan <- models.NewDocumentChanItem(models.UPDATE, slug, document.ID)
+			result.Updated = append(result.Updated, blob.path)
+			continue
+		}
+
+		document, err = this.documentsRepo.CreateDocument(c, document, uuid.Nil)
+		if err != nil {
+			return result, err
+		}
+
+		this.documentChan <- models.NewDocumentChanItem(models.CREATE, slug, document.ID)
+		result.Created = append(result.Created, blob.path)
+	}
+
+	for _, d := range documents {
+		if seen[d.Filename] || d.Metadata[models.DOCUMENT_SOURCE_KEY] != models.DOCUMENT_SOURCE_GIT || (only != nil && !only[d.Filename]) {
+			continue
+		}
+
+		if _, err = this.documentsRepo.DeleteDocument(c, slug, d.ID); err != nil {
+			return
+		}
+
+		this.documentChan <- models.NewDocumentChanItem(models.DELETE, slug, d.ID)
+		result.Deleted = append(result.Deleted, d.Filename)
+	}
+
+	if len(result.Created)+len(result.Updated)+len(result.Deleted) > 0 {
+		if _, err := this.postsRepo.BumpDocumentVersion(c, slug); err != nil {
+			slog.Error("Error bumping the document version for post with slug", "slug", slug, "error", err.Error())
+		}
+
+		if _, err := this.searchCacheRepo.DeleteEntriesFor(c, slug); err != nil {
+			slog.Error("Error invalidating the search cache for post with slug", "slug", slug, "error", err.Error())
+		}
+	}
+
+	err = this.gitSourcesRepo.SetSynced(c, slug, commit)
+
+	slog.Info("Synced post with git repository", "slug", slug, "commit", commit, "created", len(result.Created), "updated", len(result.Updated), "deleted", len(result.Deleted), "unchanged", result.Unchanged)
+
+	return
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckSource(t *testing.T) {
+	hosts := map[string][]string{
+		"public.example":   {"93.184.216.34"},
+		"internal.example": {"10.0.0.5"},
+		"metadata.example": {"169.254.169.254"},
+		"rebind.example":   {"93.184.216.34", "127.0.0.1"},
+	}
+
+	lookup := lookupIPAddr
+	lookupIPAddr = func(c context.Context, host string) ([]net.IPAddr, error) {
+		addrs := []net.IPAddr{}
+		for _, a := range hosts[host] {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		if len(addrs) == 0 {
+			return nil, errors.New("no such host")
+		}
+		return addrs, nil
+	}
+	defer func() { lookupIPAddr = lookup }()
+
+	tests := []struct {
+		url        string
+		branch     string
+		allowLocal bool
+		allowed    bool
+	}{
+		{url: "https://public.example/course.git", allowed: true},
+		{url: "git@public.example:user/course.git", allowed: true},
+		{url: "ssh://git@public.example/course.git", allowed: true},
+		{url: "https://93.184.216.34/course.git", allowed: true},
+		{url: "https://internal.example/course.git"},
+		{url: "git://metadata.example/course.git"},
+		{url: "git@internal.example:user/course.git"},
+		{url: "https://rebind.example/course.git"},
+		{url: "https://missing.example/course.git"},
+		{url: "http://127.0.0.1:8080/course.git"},
+		{url: "http://169.254.169.254/latest/meta-data"},
+		{url: "ssh://git@[::1]/course.git"},
+		{url: "https://192.168.1.10/course.git"},
+		{url: "/srv/git/course.git"},
+		{url: "file:///srv/git/course.git"},
+		{url: "--upload-pack=touch /tmp/pwned"},
+		{url: "--upload-pack=touch /tmp/pwned", allowLocal: true},
+		{url: "https://public.example/course.git", branch: "../main"},
+		{url: "https://public.example/course.git", branch: "-main"},
+		{url: "/srv/git/course.git", allowLocal: true, allowed: true},
+		{url: "http://127.0.0.1:8080/course.git", allowLocal: true, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			service := gitService{}
+			service.cfg.Git.AllowLocal = tt.allowLocal
+
+			branch := tt.branch
+			if branch == "" {
+				branch = "main"
+			}
+
+			err := service.CheckSource(context.Background(), models.GitSource{Url: tt.url, Branch: branch})
+			if tt.allowed && err != nil {
+				t.Errorf("CheckSource(%q, %q) = %v, want nil", tt.url, branch, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrGitForbidden) {
+				t.Errorf("CheckSource(%q, %q) = %v, want %v", tt.url, branch, err, ErrGitForbidden)
+			}
+		})
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{glob: "", path: "any/file.md", matches: true},
+		{glob: "*.md", path: "README.md", matches: true},
+		{glob: "*.md", path: "notes/a.md", matches: false},
+		{glob: "notes/**", path: "notes/week1/a.md", matches: true},
+		{glob: "notes/**/*.md", path: "notes/a.md", matches: true},
+		{glob: "notes/**/*.md", path: "notes/week1/a.md", matches: true},
+		{glob: "notes/**/*.md", path: "notes/week1/a.txt", matches: false},
+		{glob: "notes/?.md", path: "notes/a.md", matches: true},
+		{glob: "notes/?.md", path: "notes/ab.md", matches: false},
+		{glob: "a+b/*.md", path: "a+b/c.md", matches: true},
+	}
+
+	for _, tt := range tests {
+		if got := globRegexp(tt.glob).MatchString(tt.path); got != tt.matches {
+			t.Errorf("globRegexp(%q).MatchString(%q) = %v, want %v", tt.glob, tt.path, got, tt.matches)
+		}
+	}
+}
+
+type fakeGitSources struct {
+	repositories.GitSourcesRepository
+	source     models.GitSource
+	deliveries chan models.WebhookDelivery
+}
+
+func (this *fakeGitSources) GetSource(c context.Context, slug uuid.UUID) (models.GitSource, error) {
+	return this.source, nil
+}
+
+func (this *fakeGitSources) SetSynced(c context.Context, slug uuid.UUID, commit string) error {
+	this.source.LastCommit = commit
+	return nil
+}
+
+func (this *fakeGitSources) UpdateDelivery(c context.Context, delivery models.WebhookDelivery) error {
+	this.deliveries <- delivery
+	return nil
+}
+
+func (this *fakeDocuments) GetDocuments(c context.Context, slug uuid.UUID) ([]models.Document, error) {
+	documents := []models.Document{}
+	for _, d := range this.documents {
+		documents = append(documents, d)
+	}
+	return documents, nil
+}
+
+func (this *fakeDocuments) CreateDocument(c context.Context, document models.Document, authorId uuid.UUID) (models.Document, error) {
+	document.ID = uuid.New()
+	this.documents[document.ID] = document
+	return document, nil
+}
+
+func (this *fakeDocuments) UpdateDocument(c context.Context, slug uuid.UUID, id uuid.UUID, document models.Document, authorId uuid.UUID) (models.Document, error) {
+	document.ID = id
+	this.documents[id] = document
+	return document, nil
+}
+
+func (this *fakeDocuments) DeleteDocument(c context.Context, slug uuid.UUID, id uuid.UUID) (uuid.UUID, error) {
+	delete(this.documents, id)
+	return id, nil
+}
+
+// runGit runs git in the directory with a fixed identity.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+// commitFiles writes the files in the working copy, removing the ones with no
+// content, and pushes them to the bare repository.
+func commitFiles(t *testing.T, work string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		p := filepath.Join(work, name)
+		if content == "" {
+			runGit(t, work, "rm", "--quiet", name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, work, "add", name)
+	}
+
+	runGit(t, work, "commit", "--quiet", "-m", "update")
+	runGit(t, work, "push", "--quiet", "origin", "main")
+}
+
+func TestSyncLocalRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "course.git")
+	work := filepath.Join(root, "work")
+
+	runGit(t, root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	runGit(t, root, "clone", "--quiet", bare, work)
+	runGit(t, work, "checkout", "--quiet", "-b", "main")
+	commitFiles(t, work, map[string]string{
+		"notes/intro.md":  "# Intro\n\nWelcome.",
+		"notes/week1.md":  "# Week 1\n\nRecursion.",
+		"notes/logo.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR",
+		"scripts/run.txt": "outside the glob",
+	})
+
+	slug := uuid.New()
+	cfg := config.Config{}
+	cfg.Git.CacheDir = filepath.Join(root, "cache")
+	cfg.Git.Timeout = time.Minute
+	cfg.Git.MaxFileSize = 1 << 20
+	cfg.Git.AllowLocal = true
+
+	sources := &fakeGitSources{source: models.GitSource{PostSlug: slug, Url: bare, Branch: "main", PathGlob: "notes/**"}}
+	documents := &fakeDocuments{documents: map[uuid.UUID]models.Document{}}
+	documentChan := make(chan models.DocumentChanItem, 16)
+	service := NewGitService(cfg, sources, documents, fakePosts{}, fakeSearchCache{}, documentChan, nil)
+
+	result, err := service.Sync(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	slices.Sort(result.Created)
+	if !slices.Equal(result.Created, []string{"notes/intro.md", "notes/week1.md"}) || !slices.Equal(result.Skipped, []string{"notes/logo.png"}) {
+		t.Fatalf("Sync() created %v, skipped %v", result.Created, result.Skipped)
+	}
+	if sources.source.LastCommit != result.Commit || len(result.Commit) != 40 {
+		t.Errorf("Sync() commit = %q, saved %q", result.Commit, sources.source.LastCommit)
+	}
+
+	commitFiles(t, work, map[string]string{
+		"notes/intro.md": "# Intro\n\nWelcome back.",
+		"notes/week1.md": "",
+	})
+
+	result, err = service.Sync(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if !slices.Equal(result.Updated, []string{"notes/intro.md"}) || !slices.Equal(result.Deleted, []string{"notes/week1.md"}) || len(result.Created) != 0 {
+		t.Fatalf("Sync() created %v, updated %v, deleted %v", result.Created, result.Updated, result.Deleted)
+	}
+	if len(documents.documents) != 1 {
+		t.Errorf("Sync() left %d documents, want 1", len(documents.documents))
+	}
+
+	cfg.Git.AllowLocal = false
+	service = NewGitService(cfg, sources, documents, fakePosts{}, fakeSearchCache{}, documentChan, nil)
+	if _, err := service.Sync(context.Background(), slug); !errors.Is(err, ErrGitForbidden) {
+		t.Errorf("Sync() without git.allowLocal error = %v, want %v", err, ErrGitForbidden)
+	}
+}
+
+func TestGitWorker(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	slug := uuid.New()
+	cfg := config.Config{}
+	cfg.Git.CacheDir = t.TempDir()
+	cfg.Git.Timeout = time.Minute
+	cfg.Git.AllowLocal = true
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		ref     string
+		status  string
+		message string
+	}{
+		{name: "another branch", timeout: time.Minute, ref: "refs/heads/draft", status: models.DELIVERY_IGNORED, message: "Push to another branch"},
+		{name: "missing repository", timeout: time.Minute, ref: "refs/heads/main", status: models.DELIVERY_FAILED, message: "exit status"},
+		{name: "timeout", timeout: time.Nanosecond, ref: "refs/heads/main", status: models.DELIVERY_FAILED, message: context.DeadlineExceeded.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Git.WebhookTimeout = tt.timeout
+
+			sources := &fakeGitSources{
+				source:     models.GitSource{PostSlug: slug, Url: filepath.Join(t.TempDir(), "missing.git"), Branch: "main"},
+				deliveries: make(chan models.WebhookDelivery, 1),
+			}
+			pushChan := make(chan models.GitPushChanItem, 1)
+			service := NewGitService(cfg, sources, &fakeDocuments{}, fakePosts{}, fakeSearchCache{}, nil, pushChan)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go service.Worker(ctx)
+
+			pushChan <- models.GitPushChanItem{
+				Delivery: models.NewWebhookDelivery(slug, "1", "push"),
+				Push:     models.GitPushPayload{Ref: tt.ref, After: "0123456789abcdef"},
+			}
+
+			select {
+			case delivery := <-sources.deliveries:
+				if delivery.Status != tt.status || delivery.DeliveryID != "1" || !strings.Contains(delivery.Message, tt.message) {
+					t.Errorf("Worker() recorded %+v, want the status %q", delivery, tt.status)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Worker() did not record the delivery")
+			}
+		})
+	}
+}
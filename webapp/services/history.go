@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/google/uuid"
+)
+
+type HistoryService interface {
+	Record(c context.Context, userId uuid.UUID, slug uuid.UUID, query string, answerId uuid.UUID)
+	GetHistory(c context.Context, userId uuid.UUID, query models.SearchHistoryQuery) (models.SearchHistoryPage, error)
+	DeleteEntry(c context.Context, userId uuid.UUID, id uuid.UUID) error
+	ClearHistory(c context.Context, userId uuid.UUID) error
+	SetEnabled(c context.Context, userId uuid.UUID, enabled bool) (models.User, error)
+}
+
+type historyService struct {
+	usersRepo   repositories.UsersRepository
+	historyRepo repositories.HistoryRepository
+}
+
+func NewHistoryService(usersRepo repositories.UsersRepository, historyRepo repositories.HistoryRepository) HistoryService {
+	return historyService{usersRepo, historyRepo}
+}
+
+// Record saves the search in the history of the user, unless the user has
+// opted out of the search history. The searches of the anonymous users are
+// not recorded, their session is lost on logout so nobody could read them back.
+func (this historyService) Record(c context.Context, userId uuid.UUID, slug uuid.UUID, query string, answerId uuid.UUID) {
+	user, err := this.usersRepo.GetUser(c, userId)
+	if err != nil {
+		slog.Error("Error getting the user with id", "id", userId, "error", err.Error())
+		return
+	}
+
+	if user.IsAnonymous() || !user.SearchHistory {
+		return
+	}
+
+	_, err = this.historyRepo.CreateEntry(c, models.NewSearchHistoryEntry(userId, slug, query, answerId))
+	if err != nil {
+		slog.Error("Error saving the search history for user with id", "id", userId, "error", err.Error())
+		return
+	}
+}
+
+func (this historyService) GetHistory(c context.Context, userId uuid.UUID, query models.SearchHistoryQuery) (page models.SearchHistoryPage, err error) {
+	page.Page = query.Page
+	page.Size = query.Size
+
+	page.Items, page.Total, err = this.historyRepo.GetEntries(c, userId, (query.Page-1)*query.Size, query.Size)
+
+	return
+}
+
+func (this historyService) DeleteEntry(c context.Context, userId uuid.UUID, id uuid.UUID) (err error) {
+	_, err = this.historyRepo.DeleteEntry(c, userId, id)
+
+	return
+}
+
+func (this historyService) ClearHistory(c context.Context, userId uuid.UUID) (err error) {
+	_, err = this.historyRepo.DeleteEntriesFor(c, userId)
+
+	return
+}
+
+func (this historyService) SetEnabled(c context.Context, userId uuid.UUID, enabled bool) (models.User, error) {
+	return this.usersRepo.SetSearchHistory(c, userId, enabled)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/repositories"
+
+	"github.com/google/uuid"
+)
+
+type fakeUsers struct {
+	repositories.UsersRepository
+	users map[uuid.UUID]models.User
+}
+
+func (this fakeUsers) GetUser(c context.Context, id uuid.UUID) (models.User, error) {
+	user, ok := this.users[id]
+	if !ok {
+		return models.User{}, errors.New("no rows in result set")
+	}
+	return user, nil
+}
+
+type fakeHistory struct {
+	repositories.HistoryRepository
+	entries []models.SearchHistoryEntry
+}
+
+func (this *fakeHistory) CreateEntry(c context.Context, entry models.SearchHistoryEntry) (models.SearchHistoryEntry, error) {
+	this.entries = append(this.entries, entry)
+	return entry, nil
+}
+
+func TestRecord(t *testing.T) {
+	login := "octocat"
+	user := models.NewUser(models.GitHubUser{Login: login, Name: "Octocat"})
+	user.ID = uuid.New()
+	optedOut := models.NewUser(models.GitHubUser{Login: login, Name: "Octocat"})
+	optedOut.ID = uuid.New()
+	optedOut.SearchHistory = false
+	anonymous := models.NewAnonymousUser()
+	anonymous.ID = uuid.New()
+
+	users := fakeUsers{users: map[uuid.UUID]models.User{user.ID: user, optedOut.ID: optedOut, anonymous.ID: anonymous}}
+
+	slug, answerId := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		userId   uuid.UUID
+		answerId uuid.UUID
+		recorded bool
+	}{
+		{name: "user", userId: user.ID, answerId: answerId, recorded: true},
+		{name: "without answer", userId: user.ID, answerId: uuid.Nil, recorded: true},
+		{name: "opted out", userId: optedOut.ID},
+		{name: "anonymous", userId: anonymous.ID, answerId: answerId},
+		{name: "unknown user", userId: uuid.New(), answerId: answerId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &fakeHistory{}
+			NewHistoryService(users, history).Record(context.Background(), tt.userId, slug, "recursion", tt.answerId)
+
+			if !tt.recorded {
+				if len(history.entries) != 0 {
+					t.Errorf("Record() saved %+v, want nothing", history.entries)
+				}
+				return
+			}
+
+			if len(history.entries) != 1 {
+				t.Fatalf("Record() saved %d entries, want 1", len(history.entries))
+			}
+			entry := history.entries[0]
+			if entry.UserID != tt.userId || entry.PostSlug != slug || entry.Query != "recursion" {
+				t.Errorf("Record() saved %+v", entry)
+			}
+			if (entry.AnswerID != nil) != (tt.answerId != uuid.Nil) || (entry.AnswerID != nil && *entry.AnswerID != tt.answerId) {
+				t.Errorf("Record() answer = %v, want %v", entry.AnswerID, tt.answerId)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+	"webapp-go/webapp/config"
+	"webapp-go/webapp/models"
+	"webapp-go/webapp/parsers"
+)
+
+var (
+	ErrImportForbidden = errors.New("address not allowed")
+	ErrImportTooLarge  = errors.New("page is too large")
+	ErrImportFailed    = errors.New("could not fetch the page")
+)
+
+type ImportService interface {
+	Import(c context.Context, rawUrl string) (models.Document, error)
+}
+
+type importService struct {
+	cfg    config.Config
+	client *http.Client
+}
+
+func NewImportService(cfg config.Config) ImportService {
+	dialer := &net.Dialer{Timeout: cfg.Import.Timeout}
+	if !cfg.Import.AllowPrivateNetworks {
+		dialer.Control = denyPrivateAddresses
+	}
+
+	transport := &http.Transport{
+		// The environment proxy would make the checks apply to the proxy
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Import.Timeout,
+		ResponseHeaderTimeout: cfg.Import.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Import.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: %s", ErrImportForbidden, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+
+	return importService{cfg, client}
+}
+
+// deniedNetworks are the ranges that are not private in the sense of
+// net.IP.IsPrivate but are not reachable on the internet either.
+var deniedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// isPrivateAddress reports whether the address is a loopback, private, link
+// local or otherwise internal address, which the server must not be made to
+// connect to.
+func isPrivateAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	for _, n := range deniedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// denyPrivateAddresses refuses the connections to loopback, private and link
+// local addresses. It runs once the name is resolved, so that a name pointing
+// to an internal address cannot be used to reach it.
+func denyPrivateAddresses(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrImportForbidden, host)
+	}
+
+	return nil
+}
+
+// importFilename names the document after the address of the page, such as
+// "example.com/articles/recursion.md".
+func importFilename(u *url.URL, contentType string) string {
+	// The extension of the page is replaced, not the domain of a bare host
+	name := strings.Trim(u.Host+u.Path, "/")
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		name = strings.TrimSuffix(name, path.Ext(p))
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	extension := ".txt"
+	switch contentType {
+	case "text/html", "application/xhtml+xml", "text/markdown":
+		extension = ".md"
+	case "text/x-rst":
+		extension = ".rst"
+	}
+
+	if limit := models.DocumentFilenameMaxLength - len(extension); len(name) > limit {
+		name = name[:limit]
+	}
+
+	return name + extension
+}
+
+// Import fetches the page and extracts its text. The document is returned
+// unsaved, as markdown for web pages so that their headings are kept.
+func (this importService) Import(c context.Context, rawUrl string) (document models.Document, err error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		err = fmt.Errorf("%w: %s", ErrImportForbidden, u.Scheme)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrImportForbidden) {
+			return document, err
+		}
+		return document, fmt.Errorf("%w: %s", ErrImportFailed, err.Error())
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%w: %s", ErrImportFailed, res.Status)
+		return
+	}
+
+	if res.ContentLength > this.cfg.Import.MaxSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrImportTooLarge, this.cfg.Import.MaxSize)
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(res.Body, this.cfg.Import.MaxSize+1))
+	if err != nil {
+		return document, fmt.Errorf("%w: %s", ErrImportFailed, err.Error())
+	}
+	if int64(len(content)) > this.cfg.Import.MaxSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrImportTooLarge, this.cfg.Import.MaxSize)
+		return
+	}
+
+	// The final address is used after redirects
+	u = res.Request.URL
+
+	contentType := parsers.DetectContentType(path.Base(u.Path), content)
+	parsed, err := parsers.Parse(path.Base(u.Path), contentType, content)
+	if err != nil {
+		return
+	}
+
+	document = models.Document{
+		Filename:    importFilename(u, contentType),
+		ContentType: "text/plain",
+		Content:     []byte(parsed.Text()),
+	}
+	document.Describe(parsed)
+
+	switch contentType {
+	case "text/html", "application/xhtml+xml":
+		document.ContentType = "text/markdown"
+	case "text/markdown", "text/x-rst":
+		document.ContentType = contentType
+		document.Content = content
+	}
+
+	document.Metadata["sourceUrl"] = u.String()
+
+	return
+}
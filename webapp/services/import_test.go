@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"webapp-go/webapp/config"
+)
+
+func TestDenyPrivateAddresses(t *testing.T) {
+	tests := []struct {
+		address string
+		denied  bool
+	}{
+		{address: "127.0.0.1:80", denied: true},
+		{address: "[::1]:443", denied: true},
+		{address: "10.1.2.3:80", denied: true},
+		{address: "172.16.0.1:80", denied: true},
+		{address: "192.168.1.1:80", denied: true},
+		{address: "169.254.169.254:80", denied: true},
+		{address: "[fe80::1]:80", denied: true},
+		{address: "[fd00::1]:80", denied: true},
+		{address: "0.0.0.0:80", denied: true},
+		{address: "100.64.0.1:80", denied: true},
+		{address: "224.0.0.1:80", denied: true},
+		{address: "93.184.216.34:443", denied: false},
+		{address: "[2606:2800:220:1::1]:443", denied: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := denyPrivateAddresses("tcp", tt.address, nil)
+			if tt.denied != errors.Is(err, ErrImportForbidden) {
+				t.Errorf("denyPrivateAddresses(%q) = %v, want denied %v", tt.address, err, tt.denied)
+			}
+		})
+	}
+}
+
+func TestImportFilename(t *testing.T) {
+	tests := []struct {
+		url         string
+		contentType string
+		want        string
+	}{
+		{url: "https://example.com/articles/recursion.html", contentType: "text/html", want: "example.com/articles/recursion.md"},
+		{url: "https://example.com/", contentType: "text/html", want: "example.com.md"},
+		{url: "https://example.com/notes.rst", contentType: "text/x-rst", want: "example.com/notes.rst"},
+		{url: "https://example.com:8080/a:b", contentType: "text/plain", want: "example.com_8080/a_b.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			if got := importFilename(u, tt.contentType); got != tt.want {
+				t.Errorf("importFilename(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestImportService(allowPrivate bool, maxSize int64) ImportService {
+	cfg := config.Config{}
+	cfg.Import.Timeout = 5 * time.Second
+	cfg.Import.MaxSize = maxSize
+	cfg.Import.AllowPrivateNetworks = allowPrivate
+
+	return NewImportService(cfg)
+}
+
+func TestImport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><head><title>Recursion</title></head><body><nav>Menu</nav><h1>Recursion</h1><p>See recursion.</p></body></html>")
+		case "/moved":
+			http.Redirect(w, r, "/article", http.StatusFound)
+		case "/large":
+			fmt.Fprint(w, strings.Repeat("a", 2048))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("html page", func(t *testing.T) {
+		document, err := newTestImportService(true, 1024).Import(context.Background(), server.URL+"/moved")
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+
+		if document.ContentType != "text/markdown" || string(document.Content) != "# Recursion\n\nSee recursion." {
+			t.Errorf("Import() = %s %q", document.ContentType, document.Content)
+		}
+		if document.Title != "Recursion" || document.Metadata["sourceUrl"] != server.URL+"/article" {
+			t.Errorf("Import() title = %q, metadata = %v", document.Title, document.Metadata)
+		}
+		if !strings.HasSuffix(document.Filename, "/article.md") {
+			t.Errorf("Import() filename = %q", document.Filename)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := newTestImportService(true, 1024).Import(context.Background(), server.URL+"/large")
+		if !errors.Is(err, ErrImportTooLarge) {
+			t.Errorf("Import() error = %v, want %v", err, ErrImportTooLarge)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := newTestImportService(true, 1024).Import(context.Background(), server.URL+"/missing")
+		if !errors.Is(err, ErrImportFailed) {
+			t.Errorf("Import() error = %v, want %v", err, ErrImportFailed)
+		}
+	})
+
+	t.Run("loopback denied", func(t *testing.T) {
+		_, err := newTestImportService(false, 1024).Import(context.Background(), server.URL+"/article")
+		if !errors.Is(err, ErrImportForbidden) {
+			t.Errorf("Import() error = %v, want %v", err, ErrImportForbidden)
+		}
+	})
+
+	t.Run("scheme denied", func(t *testing.T) {
+		_, err := newTestImportService(true, 1024).Import(context.Background(), "file:///etc/passwd")
+		if !errors.Is(err, ErrImportForbidden) {
+			t.Errorf("Import() error = %v, want %v", err, ErrImportForbidden)
+		}
+	})
+}